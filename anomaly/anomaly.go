@@ -0,0 +1,305 @@
+package anomaly
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/zjbztianya/go-misc/metrics"
+)
+
+// Detector learns a series point by point and scores how far every point deviates
+// from what it expected, in standard deviations or a comparable unit.
+type Detector interface {
+	// Update learns v, the score is 0 while the detector is warming up.
+	Update(v float64) (expected, score float64)
+}
+
+// Anomaly is a point whose score exceeded the threshold of a Monitor.
+type Anomaly struct {
+	Time     time.Time
+	Value    float64
+	Expected float64
+	Score    float64
+}
+
+// Handler is notified of anomalies.
+type Handler interface {
+	HandleAnomaly(a Anomaly)
+}
+
+// HandlerFunc adapts a function to a Handler.
+type HandlerFunc func(a Anomaly)
+
+func (f HandlerFunc) HandleAnomaly(a Anomaly) {
+	f(a)
+}
+
+// Monitor feeds a series to a detector and reports the points whose absolute
+// score is greater than threshold to a handler. The handler is called in order, one
+// anomaly at a time, without holding the lock of the Monitor, so it may call it.
+type Monitor struct {
+	mu        sync.Mutex // serializes updates of the detector
+	detector  Detector
+	threshold float64
+	handler   Handler
+	lastStart time.Time // start of the last snapshot bucket observed
+	pending   []Anomaly // not handled yet
+	notifying bool      // a goroutine is calling the handler
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewMonitor(d Detector, threshold float64, h Handler) *Monitor {
+	return &Monitor{detector: d, threshold: threshold, handler: h}
+}
+
+// Observe feeds the point v at time t and reports whether it is an anomaly.
+func (m *Monitor) Observe(t time.Time, v float64) bool {
+	m.mu.Lock()
+	anomalous := m.observe(t, v)
+	m.notify()
+	return anomalous
+}
+
+func (m *Monitor) observe(t time.Time, v float64) bool {
+	expected, score := m.detector.Update(v)
+	if math.Abs(score) <= m.threshold {
+		return false
+	}
+	m.pending = append(m.pending, Anomaly{Time: t, Value: v, Expected: expected, Score: score})
+	return true
+}
+
+// notify handles the pending anomalies and unlocks m.mu, which must be held. When
+// another call is already notifying, it leaves the anomalies to that call so the
+// handler still sees them in order.
+func (m *Monitor) notify() {
+	if m.notifying {
+		m.mu.Unlock()
+		return
+	}
+	m.notifying = true
+	for len(m.pending) > 0 {
+		pending := m.pending
+		m.pending = nil
+		m.mu.Unlock()
+		for _, a := range pending {
+			m.handler.HandleAnomaly(a)
+		}
+		m.mu.Lock()
+	}
+	m.notifying = false
+	m.mu.Unlock()
+}
+
+// ObserveSnapshot feeds the sum of every bucket of s that ended since the previous
+// snapshot, the last bucket is still filling up and is left for the next one.
+func (m *Monitor) ObserveSnapshot(s *metrics.Snapshot) {
+	m.mu.Lock()
+	defer m.notify()
+	for i := 0; i < s.Len()-1; i++ {
+		start := s.BucketStart(i)
+		if !start.After(m.lastStart) {
+			continue
+		}
+		m.lastStart = start
+		m.observe(start, s.Bucket(i).Sum)
+	}
+}
+
+// Start feeds the value returned by fn every interval in the background until Stop
+// is called, such as m.Start(time.Minute, counter.Rate) for a SlidingCounter.
+func (m *Monitor) Start(interval time.Duration, fn func() float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stop != nil {
+		return
+	}
+	m.stop = make(chan struct{})
+	m.wg.Add(1)
+	go func(stop chan struct{}) {
+		defer m.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case t := <-ticker.C:
+				m.Observe(t, fn())
+			case <-stop:
+				return
+			}
+		}
+	}(m.stop)
+}
+
+func (m *Monitor) Stop() {
+	m.mu.Lock()
+	if m.stop != nil {
+		close(m.stop)
+		m.stop = nil
+	}
+	m.mu.Unlock()
+	m.wg.Wait()
+}
+
+// zScore scores a point against the mean and standard deviation of a rolling
+// window of the previous points.
+type zScore struct {
+	values []float64 // ring buffer
+	next   int
+	full   bool
+	sum    float64
+	sumSq  float64
+}
+
+// NewZScore creates a detector comparing every point to the previous size points,
+// it warms up until size points are seen.
+func NewZScore(size int) Detector {
+	if size <= 1 {
+		panic("z-score window size must greater than 1")
+	}
+	return &zScore{values: make([]float64, size)}
+}
+
+func (z *zScore) Update(v float64) (expected, score float64) {
+	if z.full {
+		n := float64(len(z.values))
+		expected = z.sum / n
+		variance := math.Max(z.sumSq/n-expected*expected, 0)
+		score = deviation(v-expected, math.Sqrt(variance))
+	}
+	old := z.values[z.next]
+	z.sum += v - old
+	z.sumSq += v*v - old*old
+	z.values[z.next] = v
+	z.next = (z.next + 1) % len(z.values)
+	if z.next == 0 {
+		z.full = true
+	}
+	return expected, score
+}
+
+// ewmaChart is an EWMA control chart: the mean and variance of the series are
+// exponentially weighted moving averages, so it adapts to slow drifts.
+type ewmaChart struct {
+	alpha    float64
+	warmup   int
+	n        int
+	mean     float64
+	variance float64
+}
+
+// NewEWMAChart creates a detector whose moving averages weigh the latest point by alpha,
+// it warms up for 1/alpha points.
+func NewEWMAChart(alpha float64) Detector {
+	if alpha <= 0 || alpha > 1 {
+		panic("ewma alpha must be in (0, 1]")
+	}
+	return &ewmaChart{alpha: alpha, warmup: int(math.Ceil(1 / alpha))}
+}
+
+func (e *ewmaChart) Update(v float64) (expected, score float64) {
+	if e.n == 0 {
+		e.mean = v
+		e.n++
+		return v, 0
+	}
+	expected = e.mean
+	diff := v - e.mean
+	if e.n >= e.warmup {
+		score = deviation(diff, math.Sqrt(e.variance))
+	}
+	// incremental exponentially weighted variance, Finch 2009
+	incr := e.alpha * diff
+	e.mean += incr
+	e.variance = (1 - e.alpha) * (e.variance + diff*incr)
+	e.n++
+	return expected, score
+}
+
+// holtWinters is the additive Holt-Winters seasonal forecast with the confidence bands
+// of Brutlag, "Aberrant Behavior Detection in Time Series for Network Monitoring":
+// the score is the forecast error divided by the smoothed error seen one season ago.
+type holtWinters struct {
+	alpha, beta, gamma float64
+	level, trend       float64
+	seasonal           []float64
+	deviations         []float64
+	n                  int
+}
+
+// NewHoltWinters creates a detector for series with a period of season points, alpha, beta
+// and gamma smooth the level, trend and seasonal components. It warms up for two seasons.
+func NewHoltWinters(alpha, beta, gamma float64, season int) Detector {
+	if season <= 1 {
+		panic("holt-winters season must greater than 1")
+	}
+	for _, f := range []float64{alpha, beta, gamma} {
+		if f < 0 || f > 1 {
+			panic("holt-winters smoothing factors must be in [0, 1]")
+		}
+	}
+	return &holtWinters{
+		alpha:      alpha,
+		beta:       beta,
+		gamma:      gamma,
+		seasonal:   make([]float64, season),
+		deviations: make([]float64, season),
+	}
+}
+
+func (h *holtWinters) Update(v float64) (expected, score float64) {
+	m := len(h.seasonal)
+	i := h.n % m
+	h.n++
+	if h.n <= m {
+		// the first season initializes the level and the seasonal components
+		h.seasonal[i] = v
+		if h.n == m {
+			for _, s := range h.seasonal {
+				h.level += s / float64(m)
+			}
+			for j := range h.seasonal {
+				h.seasonal[j] -= h.level
+			}
+		}
+		return v, 0
+	}
+
+	expected = h.level + h.trend + h.seasonal[i]
+	err := v - expected
+	if h.n > 2*m {
+		score = deviation(err, h.deviations[i])
+		h.deviations[i] = h.gamma*math.Abs(err) + (1-h.gamma)*h.deviations[i]
+	} else {
+		// the mean error of the second season initializes every deviation
+		h.deviations[i] = math.Abs(err)
+		if h.n == 2*m {
+			var sum float64
+			for _, d := range h.deviations {
+				sum += d
+			}
+			for j := range h.deviations {
+				h.deviations[j] = sum / float64(m)
+			}
+		}
+	}
+	level := h.alpha*(v-h.seasonal[i]) + (1-h.alpha)*(h.level+h.trend)
+	h.trend = h.beta*(level-h.level) + (1-h.beta)*h.trend
+	h.seasonal[i] = h.gamma*(v-level) + (1-h.gamma)*h.seasonal[i]
+	h.level = level
+	return expected, score
+}
+
+// deviation divides diff by scale, a zero scale makes any difference infinitely anomalous.
+func deviation(diff, scale float64) float64 {
+	if scale > 0 {
+		return diff / scale
+	}
+	if diff == 0 {
+		return 0
+	}
+	return math.Copysign(math.Inf(1), diff)
+}
@@ -0,0 +1,150 @@
+package anomaly
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zjbztianya/go-misc/metrics"
+)
+
+// series returns n noisy points around base, with a spike of size at index spike.
+func series(n int, base float64, spike int, size float64) []float64 {
+	r := rand.New(rand.NewSource(1))
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = base + r.NormFloat64()
+		if i == spike {
+			values[i] += size
+		}
+	}
+	return values
+}
+
+// detect returns the indexes of the points scored above threshold.
+func detect(d Detector, values []float64, threshold float64) []int {
+	var anomalies []int
+	m := NewMonitor(d, threshold, HandlerFunc(func(a Anomaly) {
+		anomalies = append(anomalies, int(a.Time.Unix()))
+	}))
+	for i, v := range values {
+		m.Observe(time.Unix(int64(i), 0), v)
+	}
+	return anomalies
+}
+
+func TestZScore(t *testing.T) {
+	assert.Panics(t, func() {
+		NewZScore(1)
+	})
+	d := NewZScore(3)
+	for _, v := range []float64{1, 2, 3} {
+		_, score := d.Update(v)
+		assert.Equal(t, 0.0, score)
+	}
+	expected, score := d.Update(2 + math.Sqrt(2.0/3))
+	assert.Equal(t, 2.0, expected)
+	assert.InDelta(t, 1.0, score, 1e-9)
+
+	assert.Equal(t, []int{150}, detect(NewZScore(50), series(200, 100, 150, 10), 5))
+}
+
+func TestEWMAChart(t *testing.T) {
+	assert.Panics(t, func() {
+		NewEWMAChart(0)
+	})
+	assert.Equal(t, []int{150}, detect(NewEWMAChart(0.05), series(200, 100, 150, 10), 5))
+
+	// a constant series has no variance, any change is an anomaly
+	d := NewEWMAChart(0.5)
+	for i := 0; i < 3; i++ {
+		d.Update(1)
+	}
+	_, score := d.Update(0.5)
+	assert.True(t, math.IsInf(score, -1))
+}
+
+func TestHoltWinters(t *testing.T) {
+	assert.Panics(t, func() {
+		NewHoltWinters(0.5, 0.1, 0.1, 1)
+	})
+	assert.Panics(t, func() {
+		NewHoltWinters(2, 0.1, 0.1, 24)
+	})
+
+	// a daily pattern with a slow trend
+	r := rand.New(rand.NewSource(1))
+	season := 24
+	values := make([]float64, 10*season)
+	for i := range values {
+		values[i] = 100 + 0.1*float64(i) + 50*math.Sin(2*math.Pi*float64(i)/float64(season)) + r.NormFloat64()
+	}
+	assert.Empty(t, detect(NewHoltWinters(0.2, 0.05, 0.2, season), values, 6))
+
+	// a dip hidden by the seasonal amplitude for a z-score
+	values[8*season+6] -= 20
+	assert.Equal(t, []int{8*season + 6}, detect(NewHoltWinters(0.2, 0.05, 0.2, season), values, 6))
+	assert.Empty(t, detect(NewZScore(season), values, 6))
+}
+
+func TestMonitorObserveSnapshot(t *testing.T) {
+	var anomalies []Anomaly
+	m := NewMonitor(NewZScore(3), 3, HandlerFunc(func(a Anomaly) {
+		anomalies = append(anomalies, a)
+	}))
+	start := time.Unix(1600000000, 0)
+	m.ObserveSnapshot(metrics.NewSnapshot(start, time.Second, []metrics.Bucket{{Sum: 10}, {Sum: 11}, {Sum: 12}, {Sum: 100}}))
+	// buckets already observed are skipped, the last one is not complete yet
+	m.ObserveSnapshot(metrics.NewSnapshot(start.Add(2*time.Second), time.Second, []metrics.Bucket{{Sum: 12}, {Sum: 50}, {Sum: 1}}))
+	assert.Len(t, anomalies, 1)
+	assert.Equal(t, start.Add(3*time.Second), anomalies[0].Time)
+	assert.Equal(t, 50.0, anomalies[0].Value)
+	assert.Equal(t, 11.0, anomalies[0].Expected)
+}
+
+func TestMonitorReentrantHandler(t *testing.T) {
+	var m *Monitor
+	var anomalies []float64
+	m = NewMonitor(NewZScore(2), 3, HandlerFunc(func(a Anomaly) {
+		anomalies = append(anomalies, a.Value)
+		if len(anomalies) == 1 {
+			// handled after this call returns
+			assert.True(t, m.Observe(a.Time.Add(time.Second), -1000))
+			assert.Equal(t, []float64{100}, anomalies)
+		}
+	}))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i, v := range []float64{1, 2, 1, 100} {
+			m.Observe(time.Unix(int64(i), 0), v)
+		}
+	}()
+	select {
+	case <-done:
+		assert.Equal(t, []float64{100, -1000}, anomalies)
+	case <-time.After(time.Second):
+		t.Fatal("handler calling Observe deadlocked")
+	}
+}
+
+func TestMonitorStart(t *testing.T) {
+	c := metrics.NewSlidingCounter(10, time.Second)
+	anomalies := make(chan Anomaly, 10)
+	m := NewMonitor(NewZScore(2), 3, HandlerFunc(func(a Anomaly) {
+		anomalies <- a
+	}))
+	m.Start(10*time.Millisecond, c.Sum)
+	m.Start(10*time.Millisecond, c.Sum)
+	defer m.Stop()
+	time.Sleep(50 * time.Millisecond)
+	c.Add(100)
+	select {
+	case a := <-anomalies:
+		assert.Equal(t, 100.0, a.Value)
+	case <-time.After(time.Second):
+		t.Fatal("no anomaly reported")
+	}
+}
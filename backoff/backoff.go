@@ -18,6 +18,10 @@ type Exponential struct {
 	cfg *Config
 }
 
+func NewExponential(cfg *Config) *Exponential {
+	return &Exponential{cfg: cfg}
+}
+
 func (e *Exponential) Backoff(retries int) time.Duration {
 	if retries == 0 {
 		return e.cfg.BaseDelay
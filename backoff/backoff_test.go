@@ -12,7 +12,7 @@ func getExpBackoff() *Exponential {
 		Multiplier: 2,
 		Jitter:     0.2,
 	}
-	return &Exponential{cfg}
+	return NewExponential(cfg)
 }
 
 func TestExponentialBackOff(t *testing.T) {
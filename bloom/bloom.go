@@ -8,9 +8,31 @@ type Filter struct {
 	bitsPerKey uint32
 	k          uint32 // k=m/n*ln2
 	bitSet     []uint64
+	hashFunc   hashkit.StringHashFunc32
+	hash128    hashkit.StringHashFunc128 // replaces hashFunc when set
 }
 
 func NewFilter(bitsPerKey int, keys ...string) *Filter {
+	return NewFilterWithHash(bitsPerKey, hashkit.Murmur32, keys...)
+}
+
+// NewFilterWithHash creates a filter hashing keys with hash, such as a keyed
+// hashkit.SipHash32 so that false positives can not be crafted.
+func NewFilterWithHash(bitsPerKey int, hash hashkit.HashFunc32, keys ...string) *Filter {
+	f := newFilter(bitsPerKey)
+	f.hashFunc = hashkit.String32(hash)
+	return f.add(keys)
+}
+
+// NewFilterWithHash128 creates a filter deriving the k bits of a key with hashkit.Indexes
+// from the two independent halves of a 128 bits hash, instead of from a single 32 bits hash.
+func NewFilterWithHash128(bitsPerKey int, hash hashkit.HashFunc128, keys ...string) *Filter {
+	f := newFilter(bitsPerKey)
+	f.hash128 = hashkit.String128(hash)
+	return f.add(keys)
+}
+
+func newFilter(bitsPerKey int) *Filter {
 	k := uint32(float64(bitsPerKey) * 0.69)
 	switch {
 	case k < 1:
@@ -18,9 +40,10 @@ func NewFilter(bitsPerKey int, keys ...string) *Filter {
 	case k > 30:
 		k = 30
 	}
+	return &Filter{bitsPerKey: uint32(bitsPerKey), k: k}
+}
 
-	f := &Filter{bitsPerKey: uint32(bitsPerKey), k: k}
-
+func (f *Filter) add(keys []string) *Filter {
 	bits := uint32(len(keys)) * f.bitsPerKey
 	if bits < 64 {
 		bits = 64
@@ -30,7 +53,14 @@ func NewFilter(bitsPerKey int, keys ...string) *Filter {
 	f.bitSet = make([]uint64, setSize)
 
 	for _, key := range keys {
-		h := hashkit.Murmur32([]byte(key))
+		if f.hash128 != nil {
+			var buf [30]uint64
+			for _, pos := range f.indexes(buf[:0], key, uint64(bits)) {
+				f.bitSet[pos/64] |= 1 << (pos % 64)
+			}
+			continue
+		}
+		h := f.hashFunc(key)
 		delta := (h >> 17) | (h << 15)
 		for i := uint32(0); i < f.k; i++ {
 			pos := h % bits
@@ -46,8 +76,18 @@ func (f *Filter) Search(key string) bool {
 		return false
 	}
 
-	h := hashkit.Murmur32([]byte(key))
 	bits := uint32(len(f.bitSet) * 64)
+	if f.hash128 != nil {
+		var buf [30]uint64
+		for _, pos := range f.indexes(buf[:0], key, uint64(bits)) {
+			if f.bitSet[pos/64]&(1<<(pos%64)) == 0 {
+				return false
+			}
+		}
+		return true
+	}
+
+	h := f.hashFunc(key)
 	delta := (h >> 17) | (h << 15)
 	for i := uint32(0); i < f.k; i++ {
 		pos := h % bits
@@ -59,3 +99,8 @@ func (f *Filter) Search(key string) bool {
 
 	return true
 }
+
+func (f *Filter) indexes(dst []uint64, key string, bits uint64) []uint64 {
+	h1, h2 := f.hash128(key)
+	return hashkit.Indexes(dst, h1, h2, int(f.k), bits)
+}
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/zjbztianya/go-misc/hashkit"
 )
 
 func TestEmptyFilter(t *testing.T) {
@@ -23,6 +24,53 @@ func TestFilterSearchSmall(t *testing.T) {
 	assert.False(t, filter.Search("world"))
 }
 
+func TestFilterWithHash(t *testing.T) {
+	hash := hashkit.SipHash32(hashkit.RandomSeed(), hashkit.RandomSeed())
+	filter := NewFilterWithHash(10, hash, "bloom", "filter")
+	assert.True(t, filter.Search("bloom"))
+	assert.True(t, filter.Search("filter"))
+	// with a random key any given key may be a false positive
+	assert.LessOrEqual(t, falsePositiveRate(filter), 0.025)
+}
+
+func TestFilterWithHash128(t *testing.T) {
+	keys := make([]string, 10000)
+	for i := range keys {
+		keys[i] = strconv.Itoa(i)
+	}
+	for _, hash := range []hashkit.HashFunc128{hashkit.Murmur128, hashkit.XXH128} {
+		filter := NewFilterWithHash128(10, hash, keys...)
+		for _, key := range keys {
+			assert.True(t, filter.Search(key))
+		}
+		assert.LessOrEqual(t, falsePositiveRate(filter), 0.0125)
+		assert.Zero(t, testing.AllocsPerRun(100, func() {
+			filter.Search("bloom")
+		}))
+	}
+}
+
+func TestFilterSearchAllocs(t *testing.T) {
+	filter := NewFilter(10, "bloom", "filter")
+	assert.Zero(t, testing.AllocsPerRun(100, func() {
+		filter.Search("bloom")
+		filter.Search("hello")
+	}))
+}
+
+func BenchmarkFilterSearch(b *testing.B) {
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = strconv.Itoa(i)
+	}
+	filter := NewFilter(10, keys...)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		filter.Search(keys[i%len(keys)])
+	}
+}
+
 func nextLen(l int) int {
 	if l < 10 {
 		l++
@@ -0,0 +1,92 @@
+// Command hashtest runs the quality tests of hashkit/quality against the hashes of
+// hashkit and prints a report per hash, e.g.
+//
+//	go run ./cmd/hashtest -hash murmur32,xxh3 -keys 1000000
+//
+// It exits with status 1 if a hash failed a test.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/zjbztianya/go-misc/hashkit"
+	"github.com/zjbztianya/go-misc/hashkit/quality"
+)
+
+// the hashes of hashkit.Lookup32 and hashkit.Lookup64, and the keyed ones with random keys
+var (
+	hashes32 = map[string]hashkit.HashFunc32{
+		"siphash32": hashkit.SipHash32(hashkit.RandomSeed(), hashkit.RandomSeed()),
+	}
+	hashes64 = map[string]hashkit.HashFunc64{
+		"siphash": hashkit.SipHash(hashkit.RandomSeed(), hashkit.RandomSeed()),
+	}
+)
+
+func init() {
+	for _, name := range hashkit.Names32() {
+		hashes32[name], _ = hashkit.Lookup32(name)
+	}
+	for _, name := range hashkit.Names64() {
+		hashes64[name], _ = hashkit.Lookup64(name)
+	}
+}
+
+func main() {
+	var (
+		names = flag.String("hash", "", "comma separated hashes to test, all if empty")
+		list  = flag.Bool("list", false, "list the hashes and exit")
+		c     quality.Config
+	)
+	flag.IntVar(&c.Keys, "keys", quality.DefaultKeys, "keys of the distribution and collision tests")
+	flag.IntVar(&c.Samples, "samples", quality.DefaultSamples, "keys of the avalanche and independence tests")
+	flag.IntVar(&c.Buckets, "buckets", quality.DefaultBuckets, "buckets of the distribution test, a power of 2")
+	flag.Int64Var(&c.Seed, "seed", 0, "seed of the random keys")
+	flag.Parse()
+
+	var all []string
+	for name := range hashes32 {
+		all = append(all, name)
+	}
+	for name := range hashes64 {
+		all = append(all, name)
+	}
+	sort.Strings(all)
+	if *list {
+		fmt.Println(strings.Join(all, "\n"))
+		return
+	}
+	if c.Buckets&(c.Buckets-1) != 0 {
+		fmt.Fprintln(os.Stderr, "hashtest: buckets must be a power of 2")
+		os.Exit(2)
+	}
+
+	selected := all
+	if *names != "" {
+		selected = strings.Split(*names, ",")
+	}
+	failed := false
+	for i, name := range selected {
+		var r *quality.Report
+		if h, ok := hashes32[name]; ok {
+			r = quality.Test32(name, h, &c)
+		} else if h, ok := hashes64[name]; ok {
+			r = quality.Test64(name, h, &c)
+		} else {
+			fmt.Fprintf(os.Stderr, "hashtest: unknown hash %q, see -list\n", name)
+			os.Exit(2)
+		}
+		if i > 0 {
+			fmt.Println()
+		}
+		r.WriteTo(os.Stdout)
+		failed = failed || !r.Passed()
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -20,13 +20,16 @@ type node struct {
 }
 
 type HashRing struct {
-	nodes    []node
-	replicas int
-	hashFunc hashkit.HashFunc32
+	nodes      []node
+	replicas   int
+	hashFunc   hashkit.HashFunc32
+	hashString hashkit.StringHashFunc32
 }
 
 type HashRingOption func(*HashRing)
 
+// WithHashFunc sets the hash of the ring, hashkit.Lookup32 selects one by name such as
+// the hash of a twemproxy configuration.
 func WithHashFunc(hash hashkit.HashFunc32) HashRingOption {
 	return func(ring *HashRing) {
 		ring.hashFunc = hash
@@ -44,6 +47,7 @@ func NewHashRing(replicas int, opts ...HashRingOption) *HashRing {
 	if h.hashFunc == nil {
 		h.hashFunc = defaultHash
 	}
+	h.hashString = hashkit.String32(h.hashFunc)
 	return h
 }
 
@@ -52,8 +56,14 @@ func (h *HashRing) AddNode(key string, replicas int) {
 		replicas = h.replicas
 	}
 
+	// the virtual nodes hash strconv.Itoa(i) + key
+	hs := hashkit.NewHash32(h.hashFunc)
+	var num [20]byte
 	for i := 0; i < replicas; i++ {
-		hash := h.hashFunc([]byte(strconv.Itoa(i) + key))
+		hs.Reset()
+		hs.Write(strconv.AppendInt(num[:0], int64(i), 10))
+		hs.WriteString(key)
+		hash := hs.Sum32()
 		h.nodes = append(h.nodes, node{
 			hash: hash,
 			key:  key,
@@ -81,7 +91,7 @@ func (h *HashRing) search(key string) (int, error) {
 		return -1, errors.New("empty ring")
 	}
 
-	hash := h.hashFunc([]byte(key))
+	hash := h.hashString(key)
 	idx := sort.Search(len(h.nodes), func(i int) bool {
 		return h.nodes[i].hash >= hash
 	}) % len(h.nodes)
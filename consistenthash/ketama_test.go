@@ -1,10 +1,12 @@
 package consistenthash
 
 import (
+	"sort"
 	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/zjbztianya/go-misc/hashkit"
 )
 
 var (
@@ -25,10 +27,38 @@ func BenchmarkHashRingGet(b *testing.B) {
 		ring.AddNode(node, replicas)
 	}
 
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = "test_key_" + strconv.Itoa(i)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		key := "test_key_" + strconv.Itoa(i)
-		ring.Get(key)
+		ring.Get(keys[i%len(keys)])
+	}
+}
+
+func TestHashRingPlacement(t *testing.T) {
+	ring := NewHashRing(replicas)
+	ring.AddNode(nodes[0], replicas)
+	var hashes []uint32
+	for i := 0; i < replicas; i++ {
+		hashes = append(hashes, hashkit.Murmur32([]byte(strconv.Itoa(i)+nodes[0])))
+	}
+	sort.Slice(hashes, func(i, j int) bool { return hashes[i] < hashes[j] })
+	for i, n := range ring.nodes {
+		assert.Equal(t, hashes[i], n.hash)
+	}
+}
+
+func TestHashRingGetAllocs(t *testing.T) {
+	ring := NewHashRing(replicas)
+	for _, node := range nodes {
+		ring.AddNode(node, replicas)
 	}
+	assert.Zero(t, testing.AllocsPerRun(100, func() {
+		ring.Get("test_key_1")
+	}))
 }
 
 func TestNewHashRing(t *testing.T) {
@@ -40,6 +70,23 @@ func TestNewHashRing(t *testing.T) {
 	assert.Equal(t, minReplicas, ring.replicas)
 }
 
+func TestHashRingWithKeyedHash(t *testing.T) {
+	k0, k1 := hashkit.RandomSeed(), hashkit.RandomSeed()
+	ring := NewHashRing(replicas, WithHashFunc(hashkit.SipHash32(k0, k1)))
+	same := NewHashRing(replicas, WithHashFunc(hashkit.SipHash32(k0, k1)))
+	for _, node := range nodes {
+		ring.AddNode(node, replicas)
+		same.AddNode(node, replicas)
+	}
+	for i := 0; i < 1000; i++ {
+		key := "test_key_" + strconv.Itoa(i)
+		host, err := ring.Get(key)
+		assert.Nil(t, err)
+		expected, _ := same.Get(key)
+		assert.Equal(t, expected, host)
+	}
+}
+
 func TestHashRingAddNode(t *testing.T) {
 	ring := NewHashRing(4)
 	ring.AddNode(nodes[0], replicas)
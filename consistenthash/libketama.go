@@ -0,0 +1,164 @@
+package consistenthash
+
+import (
+	"crypto/md5"
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/zjbztianya/go-misc/hashkit"
+)
+
+// KetamaMode selects the point generation a Ketama is compatible with.
+type KetamaMode int
+
+const (
+	// Libketama places the points like ketama.c of libketama and the PHP and Java clients.
+	Libketama KetamaMode = iota
+	// Twemproxy places the points like nc_ketama.c of twemproxy, it differs from libketama
+	// only by the float rounding of the number of points of some weights.
+	Twemproxy
+)
+
+const (
+	ketamaPointsPerHash   = 4
+	ketamaPointsPerServer = 160 // at the average weight
+)
+
+type ketamaPoint struct {
+	hash uint32
+	node int // index in nodes
+}
+
+type ketamaNode struct {
+	name   string
+	weight int
+}
+
+// Ketama is the continuum of libketama: every node gets 160 points at the average weight,
+// 4 per MD5 digest of "name-i", and a key belongs to the first point at or after its hash,
+// wrapping around to the first point. Keys map to the same nodes as libketama or twemproxy
+// clients given the same names, weights and hash: twemproxy names the nodes "host:port",
+// or "host" when the port is 11211, unless they are named in its configuration.
+type Ketama struct {
+	mode       KetamaMode
+	hashFunc   hashkit.HashFunc32
+	hashString hashkit.StringHashFunc32
+	nodes      []ketamaNode
+	points     []ketamaPoint
+}
+
+type KetamaOption func(*Ketama)
+
+func WithKetamaMode(mode KetamaMode) KetamaOption {
+	return func(k *Ketama) {
+		k.mode = mode
+	}
+}
+
+// WithKetamaHashFunc sets the hash of the keys, hashkit.Md5 like libketama by default.
+// The points are always MD5 digests.
+func WithKetamaHashFunc(hash hashkit.HashFunc32) KetamaOption {
+	return func(k *Ketama) {
+		k.hashFunc = hash
+	}
+}
+
+func NewKetama(opts ...KetamaOption) *Ketama {
+	k := &Ketama{}
+	for _, opt := range opts {
+		opt(k)
+	}
+	if k.hashFunc == nil {
+		k.hashFunc = hashkit.Md5
+	}
+	k.hashString = hashkit.String32(k.hashFunc)
+	return k
+}
+
+// AddNode adds a node or updates its weight, which must be greater than 0. As in
+// libketama every point is placed again since the points of a node depend on the
+// total weight.
+func (k *Ketama) AddNode(name string, weight int) {
+	if weight <= 0 {
+		panic("ketama node weight must greater than 0")
+	}
+	for i := range k.nodes {
+		if k.nodes[i].name == name {
+			k.nodes[i].weight = weight
+			k.build()
+			return
+		}
+	}
+	k.nodes = append(k.nodes, ketamaNode{name: name, weight: weight})
+	k.build()
+}
+
+func (k *Ketama) RemoveNode(name string) {
+	for i := range k.nodes {
+		if k.nodes[i].name == name {
+			k.nodes = append(k.nodes[:i], k.nodes[i+1:]...)
+			k.build()
+			return
+		}
+	}
+}
+
+// hashes returns the number of digests of a node, computed in single precision floats
+// like the C implementations.
+func (k *Ketama) hashes(weight, totalWeight int) int {
+	pct := float32(weight) / float32(totalWeight)
+	n := float32(len(k.nodes))
+	var hashes float32
+	if k.mode == Twemproxy {
+		// floorf((float)(pct * 160 / 4 * (float)n + 0.0000000001))
+		hashes = float32(float64(pct*ketamaPointsPerServer/ketamaPointsPerHash*n) + 0.0000000001)
+	} else {
+		// floorf(pct * 40.0 * (float)n), the product is a double
+		hashes = float32(float64(pct) * 40.0 * float64(n))
+	}
+	return int(math.Floor(float64(hashes)))
+}
+
+func (k *Ketama) build() {
+	var totalWeight int
+	for _, n := range k.nodes {
+		totalWeight += n.weight
+	}
+	k.points = k.points[:0]
+	var buf []byte
+	for i, n := range k.nodes {
+		for j := 0; j < k.hashes(n.weight, totalWeight); j++ {
+			buf = append(append(buf[:0], n.name...), '-')
+			digest := md5.Sum(strconv.AppendInt(buf, int64(j), 10))
+			for p := 0; p < ketamaPointsPerHash; p++ {
+				d := digest[p*4 : p*4+4]
+				hash := uint32(d[3])<<24 | uint32(d[2])<<16 | uint32(d[1])<<8 | uint32(d[0])
+				k.points = append(k.points, ketamaPoint{hash: hash, node: i})
+			}
+		}
+	}
+	sort.Slice(k.points, func(i, j int) bool {
+		if k.points[i].hash != k.points[j].hash {
+			return k.points[i].hash < k.points[j].hash
+		}
+		return k.points[i].node < k.points[j].node
+	})
+}
+
+// Get returns the node of key: the node of the first point at or after the hash of key,
+// or of the first point if the hash is after the last one.
+func (k *Ketama) Get(key string) (string, error) {
+	if len(k.points) == 0 {
+		return "", errors.New("empty ring")
+	}
+	hash := k.hashString(key)
+	idx := sort.Search(len(k.points), func(i int) bool {
+		return k.points[i].hash >= hash
+	})
+	if idx == len(k.points) {
+		idx = 0
+	}
+	return k.nodes[k.points[idx].node].name, nil
+}
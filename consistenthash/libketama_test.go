@@ -0,0 +1,143 @@
+package consistenthash
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zjbztianya/go-misc/hashkit"
+)
+
+// ketamaServers is the ketama.servers example of libketama.
+var ketamaServers = []struct {
+	name   string
+	weight int
+}{
+	{"10.0.1.1:11211", 600},
+	{"10.0.1.2:11211", 300},
+	{"10.0.1.3:11211", 200},
+	{"10.0.1.4:11211", 350},
+	{"10.0.1.5:11211", 1000},
+	{"10.0.1.6:11211", 800},
+	{"10.0.1.7:11211", 950},
+	{"10.0.1.8:11211", 100},
+}
+
+func ketamaPointsOf(k *Ketama) []int {
+	points := make([]int, len(k.nodes))
+	for _, p := range k.points {
+		points[p.node]++
+	}
+	return points
+}
+
+// The expected points and servers of the keys key0 to key39 were computed with the
+// continuum and lookup code of libketama and twemproxy compiled against OpenSSL MD5.
+func TestKetamaServers(t *testing.T) {
+	expected := []int{3, 6, 4, 0, 1, 5, 0, 4, 4, 4, 6, 1, 4, 4, 4, 1, 4, 1, 1, 2, 6, 4, 6, 4, 5, 3, 6, 6, 2, 2, 6, 1, 0, 6, 0, 6, 5, 4, 5, 6}
+	for _, mode := range []KetamaMode{Libketama, Twemproxy} {
+		k := NewKetama(WithKetamaMode(mode))
+		for _, s := range ketamaServers {
+			k.AddNode(s.name, s.weight)
+		}
+		assert.Equal(t, []int{176, 88, 56, 104, 296, 236, 280, 28}, ketamaPointsOf(k))
+		assert.Equal(t, uint32(762113), k.points[0].hash)
+		assert.Equal(t, uint32(4293620028), k.points[len(k.points)-1].hash)
+		for i, e := range expected {
+			server, err := k.Get("key" + strconv.Itoa(i))
+			assert.Nil(t, err)
+			assert.Equal(t, ketamaServers[e].name, server)
+		}
+	}
+}
+
+func TestKetamaHashByName(t *testing.T) {
+	// a twemproxy pool configured with "hash: fnv1a_64" and "distribution: ketama"
+	expected := []int{4, 6, 5, 2, 3, 1, 7, 5, 2, 4, 4, 4, 6, 6, 6, 7, 6, 5, 0, 6, 6, 5, 2, 0, 1, 6, 0, 4, 4, 3, 4, 3, 3, 4, 1, 4, 4, 2, 4, 5}
+	hash, err := hashkit.Lookup32("fnv1a_64")
+	assert.Nil(t, err)
+	k := NewKetama(WithKetamaMode(Twemproxy), WithKetamaHashFunc(hash))
+	for _, s := range ketamaServers {
+		k.AddNode(s.name, s.weight)
+	}
+	for i, e := range expected {
+		server, err := k.Get(strconv.Itoa(i) + ":session")
+		assert.Nil(t, err)
+		assert.Equal(t, ketamaServers[e].name, server)
+	}
+}
+
+func TestKetamaModes(t *testing.T) {
+	// 203/210*40*3 is 115.99999 in floats, twemproxy rounds it down
+	for mode, points := range map[KetamaMode]int{Libketama: 464, Twemproxy: 460} {
+		k := NewKetama(WithKetamaMode(mode))
+		k.AddNode("10.0.2.1:11211", 203)
+		k.AddNode("10.0.2.2:11211", 4)
+		k.AddNode("10.0.2.3:11211", 3)
+		assert.Equal(t, []int{points, 8, 4}, ketamaPointsOf(k))
+	}
+}
+
+func TestKetamaWraparound(t *testing.T) {
+	// the keys are their hash
+	hash := func(key []byte) uint32 {
+		h, _ := strconv.ParseUint(string(key), 10, 32)
+		return uint32(h)
+	}
+	k := NewKetama(WithKetamaHashFunc(hash))
+	_, err := k.Get("0")
+	assert.NotNil(t, err)
+	for _, name := range []string{"10.0.3.1", "10.0.3.2", "10.0.3.3"} {
+		k.AddNode(name, 1)
+	}
+	assert.Equal(t, []int{160, 160, 160}, ketamaPointsOf(k))
+	for key, server := range map[string]string{
+		"0":          "10.0.3.1",
+		"12022866":   "10.0.3.1", // the first point
+		"4244131375": "10.0.3.3", // the last point
+		"4244131376": "10.0.3.1",
+		"4294967295": "10.0.3.1",
+	} {
+		s, err := k.Get(key)
+		assert.Nil(t, err)
+		assert.Equal(t, server, s, key)
+	}
+}
+
+func TestKetamaUpdateNodes(t *testing.T) {
+	k := NewKetama()
+	k.AddNode("cache1:11212", 100)
+	k.AddNode("cache2:11212", 100)
+	k.AddNode("cache3:11212", 100)
+	k.AddNode("cache3:11212", 200)
+	assert.Equal(t, []int{120, 120, 240}, ketamaPointsOf(k))
+	expected := []int{0, 2, 0, 2, 0, 0, 2, 0, 2, 0, 1, 1, 1, 1, 0, 2, 1, 1, 0, 2}
+	for i, e := range expected {
+		server, _ := k.Get("key" + strconv.Itoa(i))
+		assert.Equal(t, "cache"+strconv.Itoa(e+1)+":11212", server)
+	}
+
+	k.RemoveNode("cache2:11212")
+	assert.Len(t, k.nodes, 2)
+	for i := 0; i < 100; i++ {
+		server, _ := k.Get("key" + strconv.Itoa(i))
+		assert.NotEqual(t, "cache2:11212", server)
+	}
+	assert.Panics(t, func() { k.AddNode("cache4:11212", 0) })
+}
+
+func BenchmarkKetamaGet(b *testing.B) {
+	k := NewKetama()
+	for _, s := range ketamaServers {
+		k.AddNode(s.name, s.weight)
+	}
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = "key" + strconv.Itoa(i)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		k.Get(keys[i%len(keys)])
+	}
+}
@@ -55,8 +55,8 @@ func NewMaglev(nodes []string, numBuckets uint64, h1, h2 hashkit.HashFunc64) (*M
 // 4.(i - j ) * skip / x == m
 // Since 1 <= skip < m, 1 <= (i - j) < m, and m is a prime number, Equation 4 cannot hold.
 func (m *Maglev) generatePermutation(node string) []uint32 {
-	offset := m.h1([]byte(node)) % m.numBuckets
-	skip := m.h2([]byte(node))%(m.numBuckets-1) + 1
+	offset := hashkit.String64(m.h1)(node) % m.numBuckets
+	skip := hashkit.String64(m.h2)(node)%(m.numBuckets-1) + 1
 	permutation := make([]uint32, m.numBuckets)
 
 	for j := uint64(0); j < m.numBuckets; j++ {
@@ -0,0 +1,231 @@
+package countmin
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/bits"
+
+	"github.com/zjbztianya/go-misc/hashkit"
+)
+
+const encodingVersion = 1
+
+var defaultHash = hashkit.Murmur64
+
+var errDimensions = errors.New("count-min sketch dimensions differ")
+
+// Sketch is a Count-Min sketch with conservative update, see Cormode and Muthukrishnan,
+// "An Improved Data Stream Summary: The Count-Min Sketch and its Applications".
+// Estimates never undercount, and overcount by at most epsilon times the total count
+// with probability 1-delta. A Sketch is not safe for concurrent use.
+type Sketch struct {
+	width      uint32
+	depth      uint32
+	counts     []uint64 // depth rows of width counters
+	total      uint64
+	hashFunc   hashkit.HashFunc64
+	hashString hashkit.StringHashFunc64
+
+	agingSamples uint64 // halve every counter after this many additions, 0 disables aging
+	samples      uint64 // additions since the last halving
+}
+
+type Option func(*Sketch)
+
+func WithHashFunc(hash hashkit.HashFunc64) Option {
+	return func(s *Sketch) {
+		s.hashFunc = hash
+	}
+}
+
+// WithAging halves every counter once samples were added since the last halving, so
+// the sketch follows recent frequencies like the reset of TinyLFU.
+func WithAging(samples uint64) Option {
+	return func(s *Sketch) {
+		s.agingSamples = samples
+	}
+}
+
+// New creates a sketch of depth rows of width counters.
+func New(width, depth int, opts ...Option) *Sketch {
+	if width <= 0 || depth <= 0 {
+		panic("count-min sketch width and depth must greater than 0")
+	}
+	s := &Sketch{width: uint32(width), depth: uint32(depth), counts: make([]uint64, width*depth)}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.hashFunc == nil {
+		s.hashFunc = defaultHash
+	}
+	s.hashString = hashkit.String64(s.hashFunc)
+	return s
+}
+
+// NewWithEstimates creates a sketch overcounting by at most epsilon times the total count
+// with probability 1-delta: width is e/epsilon and depth ln(1/delta).
+func NewWithEstimates(epsilon, delta float64, opts ...Option) *Sketch {
+	if epsilon <= 0 || delta <= 0 || delta >= 1 {
+		panic("count-min sketch epsilon must greater than 0 and delta be in (0, 1)")
+	}
+	width := int(math.Ceil(math.E / epsilon))
+	depth := int(math.Ceil(math.Log(1 / delta)))
+	return New(width, depth, opts...)
+}
+
+func (s *Sketch) Width() int {
+	return int(s.width)
+}
+
+func (s *Sketch) Depth() int {
+	return int(s.depth)
+}
+
+// Total returns the sum of all counts added, halved with the counters.
+func (s *Sketch) Total() uint64 {
+	return s.total
+}
+
+// indexes derives the counter of every row from a single 64 bits hash, see Kirsch and
+// Mitzenmacher, "Less Hashing, Same Performance: Building a Better Bloom Filter".
+func (s *Sketch) indexes(h uint64, fn func(i int)) {
+	h1, h2 := uint32(h), uint32(h>>32)
+	for row := uint32(0); row < s.depth; row++ {
+		fn(int(row)*int(s.width) + int((h1+row*h2)%s.width))
+	}
+}
+
+func (s *Sketch) AddString(key string, n uint64) {
+	s.add(s.hashString(key), n)
+}
+
+// Add counts key n times. Only the smallest counters of key are increased, which is
+// the conservative update: it lowers the overcount without breaking the guarantees.
+func (s *Sketch) Add(key []byte, n uint64) {
+	s.add(s.hashFunc(key), n)
+}
+
+func (s *Sketch) add(h uint64, n uint64) {
+	estimate := addSaturated(s.estimate(h), n)
+	s.indexes(h, func(i int) {
+		if s.counts[i] < estimate {
+			s.counts[i] = estimate
+		}
+	})
+	s.total = addSaturated(s.total, n)
+
+	if s.agingSamples > 0 {
+		s.samples = addSaturated(s.samples, n)
+		if s.samples >= s.agingSamples {
+			s.Halve()
+		}
+	}
+}
+
+func (s *Sketch) EstimateString(key string) uint64 {
+	return s.estimate(s.hashString(key))
+}
+
+// Estimate returns how many times key was added, possibly more.
+func (s *Sketch) Estimate(key []byte) uint64 {
+	return s.estimate(s.hashFunc(key))
+}
+
+func (s *Sketch) estimate(h uint64) uint64 {
+	estimate := uint64(math.MaxUint64)
+	s.indexes(h, func(i int) {
+		if s.counts[i] < estimate {
+			estimate = s.counts[i]
+		}
+	})
+	return estimate
+}
+
+// Halve divides every counter by 2, the aging of WithAging.
+func (s *Sketch) Halve() {
+	for i := range s.counts {
+		s.counts[i] >>= 1
+	}
+	s.total >>= 1
+	s.samples = 0
+}
+
+// Merge adds the counts of o to s, both must have the same dimensions and hash function.
+func (s *Sketch) Merge(o *Sketch) error {
+	if s.width != o.width || s.depth != o.depth {
+		return errDimensions
+	}
+	for i, c := range o.counts {
+		s.counts[i] = addSaturated(s.counts[i], c)
+	}
+	s.total = addSaturated(s.total, o.total)
+	return nil
+}
+
+// addSaturated returns a+b, or math.MaxUint64 instead of wrapping around so that
+// estimates never drop below the true count.
+func addSaturated(a, b uint64) uint64 {
+	sum, carry := bits.Add64(a, b, 0)
+	if carry != 0 {
+		return math.MaxUint64
+	}
+	return sum
+}
+
+// MarshalBinary encodes the dimensions, the total and the counters as varints. The hash
+// function and aging are not encoded, the decoding sketch must use the same hash function.
+func (s *Sketch) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, 1, 1+binary.MaxVarintLen64*3+len(s.counts))
+	buf[0] = encodingVersion
+	var tmp [binary.MaxVarintLen64]byte
+	for _, v := range []uint64{uint64(s.width), uint64(s.depth), s.total} {
+		buf = append(buf, tmp[:binary.PutUvarint(tmp[:], v)]...)
+	}
+	for _, c := range s.counts {
+		buf = append(buf, tmp[:binary.PutUvarint(tmp[:], c)]...)
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary decodes data into s, keeping its hash function and aging.
+func (s *Sketch) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 || data[0] != encodingVersion {
+		return errors.New("count-min sketch: unsupported encoding")
+	}
+	data = data[1:]
+	next := func() (uint64, bool) {
+		v, n := binary.Uvarint(data)
+		if n <= 0 {
+			return 0, false
+		}
+		data = data[n:]
+		return v, true
+	}
+	width, ok1 := next()
+	depth, ok2 := next()
+	total, ok3 := next()
+	// every counter takes at least a byte
+	if !ok1 || !ok2 || !ok3 || width == 0 || depth == 0 || width > math.MaxUint32 ||
+		depth > math.MaxUint32 || width*depth > uint64(len(data)) {
+		return errors.New("count-min sketch: invalid dimensions")
+	}
+	counts := make([]uint64, width*depth)
+	for i := range counts {
+		c, ok := next()
+		if !ok {
+			return errors.New("count-min sketch: invalid counter")
+		}
+		counts[i] = c
+	}
+	if len(data) != 0 {
+		return errors.New("count-min sketch: trailing data")
+	}
+
+	s.width, s.depth, s.total, s.counts, s.samples = uint32(width), uint32(depth), total, counts, 0
+	if s.hashFunc == nil {
+		s.hashFunc = defaultHash
+	}
+	s.hashString = hashkit.String64(s.hashFunc)
+	return nil
+}
@@ -0,0 +1,152 @@
+package countmin
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zjbztianya/go-misc/hashkit"
+)
+
+func TestNew(t *testing.T) {
+	s := NewWithEstimates(0.001, 0.01)
+	assert.Equal(t, 2719, s.Width())
+	assert.Equal(t, 5, s.Depth())
+	assert.Equal(t, uint64(0), s.EstimateString("a"))
+	assert.Panics(t, func() {
+		New(0, 1)
+	})
+	assert.Panics(t, func() {
+		NewWithEstimates(0.01, 1)
+	})
+}
+
+func TestSketchEstimate(t *testing.T) {
+	epsilon := 0.001
+	s := NewWithEstimates(epsilon, 0.001)
+	exact := make(map[string]uint64)
+	// a zipf-like distribution: key i is added 1000/(i+1) times
+	for i := 0; i < 10000; i++ {
+		key := "key-" + strconv.Itoa(i)
+		n := uint64(1000 / (i + 1))
+		if n == 0 {
+			n = 1
+		}
+		s.AddString(key, n)
+		exact[key] = n
+	}
+
+	var total uint64
+	for _, n := range exact {
+		total += n
+	}
+	assert.Equal(t, total, s.Total())
+	bound := uint64(epsilon * float64(total))
+	for key, n := range exact {
+		estimate := s.EstimateString(key)
+		assert.GreaterOrEqual(t, estimate, n)
+		assert.LessOrEqual(t, estimate, n+bound, key)
+	}
+	assert.Equal(t, uint64(1000), s.EstimateString("key-0"))
+}
+
+func TestSketchConservativeUpdate(t *testing.T) {
+	// a, b and c share counters: a and b in the first row, b and c in the second one
+	columns := map[string]uint64{"a": 1<<32 | 0, "b": 2<<32 | 0, "c": 1<<32 | 1}
+	s := New(8, 2, WithHashFunc(func(key []byte) uint64 {
+		return columns[string(key)]
+	}))
+	s.AddString("a", 5)
+	s.AddString("b", 3)
+	s.AddString("c", 1)
+	assert.Equal(t, uint64(5), s.EstimateString("a"))
+	// a plain update would estimate 4
+	assert.Equal(t, uint64(3), s.EstimateString("b"))
+	assert.Equal(t, uint64(1), s.EstimateString("c"))
+}
+
+func TestSketchHalve(t *testing.T) {
+	s := New(100, 4, WithAging(100))
+	s.AddString("a", 50)
+	s.AddString("b", 49)
+	assert.Equal(t, uint64(50), s.EstimateString("a"))
+	s.AddString("b", 1)
+	assert.Equal(t, uint64(25), s.EstimateString("a"))
+	assert.Equal(t, uint64(25), s.EstimateString("b"))
+	assert.Equal(t, uint64(50), s.Total())
+
+	s.Halve()
+	assert.Equal(t, uint64(12), s.EstimateString("a"))
+}
+
+func TestSketchMerge(t *testing.T) {
+	a, b := New(1000, 4), New(1000, 4)
+	a.AddString("x", 3)
+	b.AddString("x", 4)
+	b.AddString("y", 1)
+	assert.Nil(t, a.Merge(b))
+	assert.Equal(t, uint64(7), a.EstimateString("x"))
+	assert.Equal(t, uint64(1), a.EstimateString("y"))
+	assert.Equal(t, uint64(8), a.Total())
+	assert.Equal(t, errDimensions, a.Merge(New(1000, 3)))
+}
+
+func TestSketchMarshalBinary(t *testing.T) {
+	s := New(100, 3, WithHashFunc(hashkit.Fnv64))
+	for i := 0; i < 1000; i++ {
+		s.AddString(strconv.Itoa(i%50), uint64(i))
+	}
+	data, err := s.MarshalBinary()
+	assert.Nil(t, err)
+
+	decoded := New(1, 1, WithHashFunc(hashkit.Fnv64))
+	assert.Nil(t, decoded.UnmarshalBinary(data))
+	assert.Equal(t, s.counts, decoded.counts)
+	assert.Equal(t, s.Total(), decoded.Total())
+	assert.Equal(t, s.EstimateString("7"), decoded.EstimateString("7"))
+
+	var empty Sketch
+	assert.Nil(t, empty.UnmarshalBinary(data))
+	assert.Equal(t, 100, empty.Width())
+
+	assert.NotNil(t, empty.UnmarshalBinary(nil))
+	assert.NotNil(t, empty.UnmarshalBinary(data[:len(data)-1]))
+	assert.NotNil(t, empty.UnmarshalBinary(append(data, 0)))
+	assert.NotNil(t, empty.UnmarshalBinary([]byte{encodingVersion, 0, 1, 0}))
+}
+
+func TestSketchOverflow(t *testing.T) {
+	s := New(10, 2)
+	s.AddString("a", math.MaxUint64-1)
+	assert.Equal(t, uint64(math.MaxUint64-1), s.EstimateString("a"))
+
+	// counts saturate instead of wrapping around
+	s.AddString("a", 5)
+	assert.Equal(t, uint64(math.MaxUint64), s.EstimateString("a"))
+	assert.Equal(t, uint64(math.MaxUint64), s.Total())
+	assert.Nil(t, s.Merge(s))
+	assert.Equal(t, uint64(math.MaxUint64), s.EstimateString("a"))
+	assert.Equal(t, uint64(math.MaxUint64), s.Total())
+}
+
+func BenchmarkSketchAdd(b *testing.B) {
+	keys := make([][]byte, 1024)
+	for i := range keys {
+		keys[i] = []byte(strconv.Itoa(i))
+	}
+	s := NewWithEstimates(0.0001, 0.01)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Add(keys[i%len(keys)], 1)
+	}
+}
+
+func TestStringAllocs(t *testing.T) {
+	s := New(1024, 4)
+	assert.Zero(t, testing.AllocsPerRun(100, func() {
+		s.AddString("key", 1)
+		s.EstimateString("key")
+	}))
+	assert.Equal(t, s.Estimate([]byte("key")), s.EstimateString("key"))
+}
@@ -3,6 +3,7 @@ module github.com/zjbztianya/go-misc
 go 1.16
 
 require (
+	github.com/golang/snappy v1.0.0
 	github.com/spaolacci/murmur3 v1.1.0
 	github.com/stretchr/testify v1.7.0
 )
@@ -0,0 +1,246 @@
+package hashkit
+
+import "math/bits"
+
+// Primes and Murmur3 constants shared by CityHash and FarmHash.
+const (
+	cityK0 uint64 = 0xc3a5c85c97cb3127
+	cityK1 uint64 = 0xb492b66fbe98f273
+	cityK2 uint64 = 0x9ae16a3b2f90404f
+
+	cityC1 uint32 = 0xcc9e2d51
+	cityC2 uint32 = 0x1b873593
+)
+
+// City64 is CityHash64 version 1.1 of Google, see https://github.com/google/cityhash.
+func City64(data []byte) uint64 {
+	s := data
+	n := len(s)
+	switch {
+	case n <= 16:
+		return cityHashLen0to16(s)
+	case n <= 32:
+		return cityHashLen17to32(s)
+	case n <= 64:
+		return cityHashLen33to64(s)
+	}
+
+	x := read64(s, n-40)
+	y := read64(s, n-16) + read64(s, n-56)
+	z := cityHashLen16(read64(s, n-48)+uint64(n), read64(s, n-24))
+	v1, v2 := cityWeakHashLen32(s[n-64:], uint64(n), z)
+	w1, w2 := cityWeakHashLen32(s[n-32:], y+cityK1, x)
+	x = x*cityK1 + read64(s, 0)
+
+	// v and w start from the last 64 bytes, then every 64 bytes chunk is mixed in
+	for i := (n - 1) / 64; i > 0; i, s = i-1, s[64:] {
+		x = bits.RotateLeft64(x+y+v1+read64(s, 8), -37) * cityK1
+		y = bits.RotateLeft64(y+v2+read64(s, 48), -42) * cityK1
+		x ^= w2
+		y += v1 + read64(s, 40)
+		z = bits.RotateLeft64(z+w1, -33) * cityK1
+		v1, v2 = cityWeakHashLen32(s, v2*cityK1, x+w1)
+		w1, w2 = cityWeakHashLen32(s[32:], z+w2, y+read64(s, 16))
+		z, x = x, z
+	}
+	return cityHashLen16(cityHashLen16(v1, w1)+cityShiftMix(y)*cityK1+z, cityHashLen16(v2, w2)+x)
+}
+
+// City32 is CityHash32 version 1.1 of Google.
+func City32(data []byte) uint32 {
+	s := data
+	n := len(s)
+	switch {
+	case n <= 4:
+		return cityHash32Len0to4(s)
+	case n <= 12:
+		return cityHash32Len5to12(s)
+	case n <= 24:
+		return cityHash32Len13to24(s)
+	}
+
+	h := uint32(n)
+	g := cityC1 * uint32(n)
+	f := g
+	a0 := bits.RotateLeft32(read32(s, n-4)*cityC1, -17) * cityC2
+	a1 := bits.RotateLeft32(read32(s, n-8)*cityC1, -17) * cityC2
+	a2 := bits.RotateLeft32(read32(s, n-16)*cityC1, -17) * cityC2
+	a3 := bits.RotateLeft32(read32(s, n-12)*cityC1, -17) * cityC2
+	a4 := bits.RotateLeft32(read32(s, n-20)*cityC1, -17) * cityC2
+	h ^= a0
+	h = bits.RotateLeft32(h, -19)*5 + 0xe6546b64
+	h ^= a2
+	h = bits.RotateLeft32(h, -19)*5 + 0xe6546b64
+	g ^= a1
+	g = bits.RotateLeft32(g, -19)*5 + 0xe6546b64
+	g ^= a3
+	g = bits.RotateLeft32(g, -19)*5 + 0xe6546b64
+	f += a4
+	f = bits.RotateLeft32(f, -19)*5 + 0xe6546b64
+	for i := (n - 1) / 20; i > 0; i, s = i-1, s[20:] {
+		a0 := bits.RotateLeft32(read32(s, 0)*cityC1, -17) * cityC2
+		a1 := read32(s, 4)
+		a2 := bits.RotateLeft32(read32(s, 8)*cityC1, -17) * cityC2
+		a3 := bits.RotateLeft32(read32(s, 12)*cityC1, -17) * cityC2
+		a4 := read32(s, 16)
+		h ^= a0
+		h = bits.RotateLeft32(h, -18)*5 + 0xe6546b64
+		f += a1
+		f = bits.RotateLeft32(f, -19) * cityC1
+		g += a2
+		g = bits.RotateLeft32(g, -18)*5 + 0xe6546b64
+		h ^= a3 + a1
+		h = bits.RotateLeft32(h, -19)*5 + 0xe6546b64
+		g ^= a4
+		g = bits.ReverseBytes32(g) * 5
+		h += a4 * 5
+		h = bits.ReverseBytes32(h)
+		f += a0
+		f, g, h = g, h, f
+	}
+	g = bits.RotateLeft32(g, -11) * cityC1
+	g = bits.RotateLeft32(g, -17) * cityC1
+	f = bits.RotateLeft32(f, -11) * cityC1
+	f = bits.RotateLeft32(f, -17) * cityC1
+	h = bits.RotateLeft32(h+g, -19)*5 + 0xe6546b64
+	h = bits.RotateLeft32(h, -17) * cityC1
+	h = bits.RotateLeft32(h+f, -19)*5 + 0xe6546b64
+	h = bits.RotateLeft32(h, -17) * cityC1
+	return h
+}
+
+func cityShiftMix(v uint64) uint64 {
+	return v ^ v>>47
+}
+
+// cityHashLen16 is Hash128to64 of CityHash.
+func cityHashLen16(u, v uint64) uint64 {
+	return cityHashLen16Mul(u, v, 0x9ddfea08eb382d69)
+}
+
+func cityHashLen16Mul(u, v, mul uint64) uint64 {
+	a := (u ^ v) * mul
+	a ^= a >> 47
+	b := (v ^ a) * mul
+	b ^= b >> 47
+	return b * mul
+}
+
+func cityHashLen0to16(s []byte) uint64 {
+	n := len(s)
+	if n >= 8 {
+		mul := cityK2 + uint64(n)*2
+		a := read64(s, 0) + cityK2
+		b := read64(s, n-8)
+		c := bits.RotateLeft64(b, -37)*mul + a
+		d := (bits.RotateLeft64(a, -25) + b) * mul
+		return cityHashLen16Mul(c, d, mul)
+	}
+	if n >= 4 {
+		mul := cityK2 + uint64(n)*2
+		a := uint64(read32(s, 0))
+		return cityHashLen16Mul(uint64(n)+a<<3, uint64(read32(s, n-4)), mul)
+	}
+	if n > 0 {
+		y := uint32(s[0]) + uint32(s[n>>1])<<8
+		z := uint32(n) + uint32(s[n-1])<<2
+		return cityShiftMix(uint64(y)*cityK2^uint64(z)*cityK0) * cityK2
+	}
+	return cityK2
+}
+
+func cityHashLen17to32(s []byte) uint64 {
+	n := len(s)
+	mul := cityK2 + uint64(n)*2
+	a := read64(s, 0) * cityK1
+	b := read64(s, 8)
+	c := read64(s, n-8) * mul
+	d := read64(s, n-16) * cityK2
+	return cityHashLen16Mul(bits.RotateLeft64(a+b, -43)+bits.RotateLeft64(c, -30)+d,
+		a+bits.RotateLeft64(b+cityK2, -18)+c, mul)
+}
+
+func cityHashLen33to64(s []byte) uint64 {
+	n := len(s)
+	mul := cityK2 + uint64(n)*2
+	a := read64(s, 0) * cityK2
+	b := read64(s, 8)
+	c := read64(s, n-24)
+	d := read64(s, n-32)
+	e := read64(s, 16) * cityK2
+	f := read64(s, 24) * 9
+	g := read64(s, n-8)
+	h := read64(s, n-16) * mul
+	u := bits.RotateLeft64(a+g, -43) + (bits.RotateLeft64(b, -30)+c)*9
+	v := ((a + g) ^ d) + f + 1
+	w := bits.ReverseBytes64((u+v)*mul) + h
+	x := bits.RotateLeft64(e+f, -42) + c
+	y := (bits.ReverseBytes64((v+w)*mul) + g) * mul
+	z := e + f + c
+	a = bits.ReverseBytes64((x+z)*mul+y) + b
+	b = cityShiftMix((z+a)*mul+d+h) * mul
+	return b + x
+}
+
+// cityWeakHashLen32 returns a 16 bytes hash of s[0:32], a and b.
+func cityWeakHashLen32(s []byte, a, b uint64) (uint64, uint64) {
+	w, x, y, z := read64(s, 0), read64(s, 8), read64(s, 16), read64(s, 24)
+	a += w
+	b = bits.RotateLeft64(b+a+z, -21)
+	c := a
+	a += x
+	a += y
+	b += bits.RotateLeft64(a, -44)
+	return a + z, b + c
+}
+
+func cityFmix(h uint32) uint32 {
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
+}
+
+// cityMur combines a into h like Murmur3.
+func cityMur(a, h uint32) uint32 {
+	a *= cityC1
+	a = bits.RotateLeft32(a, -17)
+	a *= cityC2
+	h ^= a
+	h = bits.RotateLeft32(h, -19)
+	return h*5 + 0xe6546b64
+}
+
+func cityHash32Len0to4(s []byte) uint32 {
+	b, c := uint32(0), uint32(9)
+	for _, v := range s {
+		// bytes are signed chars in the reference implementation
+		b = b*cityC1 + uint32(int8(v))
+		c ^= b
+	}
+	return cityFmix(cityMur(b, cityMur(uint32(len(s)), c)))
+}
+
+func cityHash32Len5to12(s []byte) uint32 {
+	n := len(s)
+	a, b, c := uint32(n), uint32(n)*5, uint32(9)
+	d := b
+	a += read32(s, 0)
+	b += read32(s, n-4)
+	c += read32(s, (n>>1)&4)
+	return cityFmix(cityMur(c, cityMur(b, cityMur(a, d))))
+}
+
+func cityHash32Len13to24(s []byte) uint32 {
+	n := len(s)
+	a := read32(s, (n>>1)-4)
+	b := read32(s, 4)
+	c := read32(s, n-8)
+	d := read32(s, n>>1)
+	e := read32(s, 0)
+	f := read32(s, n-4)
+	h := uint32(n)
+	return cityFmix(cityMur(f, cityMur(e, cityMur(d, cityMur(c, cityMur(b, cityMur(a, h)))))))
+}
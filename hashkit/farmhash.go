@@ -0,0 +1,140 @@
+package hashkit
+
+import "math/bits"
+
+// Farm64 is the 64 bits fingerprint of FarmHash of Google, Fingerprint64 of
+// https://github.com/google/farmhash, which never changes across versions and platforms.
+func Farm64(data []byte) uint64 {
+	s := data
+	n := len(s)
+	switch {
+	case n <= 16:
+		return cityHashLen0to16(s)
+	case n <= 32:
+		return cityHashLen17to32(s)
+	case n <= 64:
+		return farmHashLen33to64(s)
+	}
+
+	seed := uint64(81)
+	var v1, v2, w1, w2 uint64
+	x := seed*cityK2 + read64(s, 0)
+	y := seed*cityK1 + 113
+	z := cityShiftMix(y*cityK2+113) * cityK2
+	last64 := s[n-64:]
+	for ; len(s) > 64; s = s[64:] {
+		x = bits.RotateLeft64(x+y+v1+read64(s, 8), -37) * cityK1
+		y = bits.RotateLeft64(y+v2+read64(s, 48), -42) * cityK1
+		x ^= w2
+		y += v1 + read64(s, 40)
+		z = bits.RotateLeft64(z+w1, -33) * cityK1
+		v1, v2 = cityWeakHashLen32(s, v2*cityK1, x+w1)
+		w1, w2 = cityWeakHashLen32(s[32:], z+w2, y+read64(s, 16))
+		z, x = x, z
+	}
+
+	// the last 64 bytes may overlap the last chunk
+	s = last64
+	mul := cityK1 + (z&0xff)<<1
+	w1 += uint64(n-1) & 63
+	v1 += w1
+	w1 += v1
+	x = bits.RotateLeft64(x+y+v1+read64(s, 8), -37) * mul
+	y = bits.RotateLeft64(y+v2+read64(s, 48), -42) * mul
+	x ^= w2 * 9
+	y += v1*9 + read64(s, 40)
+	z = bits.RotateLeft64(z+w1, -33) * mul
+	v1, v2 = cityWeakHashLen32(s, v2*mul, x+w1)
+	w1, w2 = cityWeakHashLen32(s[32:], z+w2, y+read64(s, 16))
+	z, x = x, z
+	return cityHashLen16Mul(cityHashLen16Mul(v1, w1, mul)+cityShiftMix(y)*cityK0+z,
+		cityHashLen16Mul(v2, w2, mul)+x, mul)
+}
+
+// Farm32 is the 32 bits fingerprint of FarmHash, Fingerprint32 of the reference implementation.
+func Farm32(data []byte) uint32 {
+	s := data
+	n := len(s)
+	switch {
+	case n <= 4:
+		return cityHash32Len0to4(s)
+	case n <= 12:
+		return cityHash32Len5to12(s)
+	case n <= 24:
+		return farmHash32Len13to24(s)
+	}
+
+	h := uint32(n)
+	g := cityC1 * uint32(n)
+	f := g
+	a0 := bits.RotateLeft32(read32(s, n-4)*cityC1, -17) * cityC2
+	a1 := bits.RotateLeft32(read32(s, n-8)*cityC1, -17) * cityC2
+	a2 := bits.RotateLeft32(read32(s, n-16)*cityC1, -17) * cityC2
+	a3 := bits.RotateLeft32(read32(s, n-12)*cityC1, -17) * cityC2
+	a4 := bits.RotateLeft32(read32(s, n-20)*cityC1, -17) * cityC2
+	h ^= a0
+	h = bits.RotateLeft32(h, -19)*5 + 0xe6546b64
+	h ^= a2
+	h = bits.RotateLeft32(h, -19)*5 + 0xe6546b64
+	g ^= a1
+	g = bits.RotateLeft32(g, -19)*5 + 0xe6546b64
+	g ^= a3
+	g = bits.RotateLeft32(g, -19)*5 + 0xe6546b64
+	f += a4
+	f = bits.RotateLeft32(f, -19) + 113
+	for ; len(s) > 20; s = s[20:] {
+		a, b, c, d, e := read32(s, 0), read32(s, 4), read32(s, 8), read32(s, 12), read32(s, 16)
+		h += a
+		g += b
+		f += c
+		h = cityMur(d, h) + e
+		g = cityMur(c, g) + a
+		f = cityMur(b+e*cityC1, f) + d
+		f += g
+		g += f
+	}
+	g = bits.RotateLeft32(g, -11) * cityC1
+	g = bits.RotateLeft32(g, -17) * cityC1
+	f = bits.RotateLeft32(f, -11) * cityC1
+	f = bits.RotateLeft32(f, -17) * cityC1
+	h = bits.RotateLeft32(h+g, -19)*5 + 0xe6546b64
+	h = bits.RotateLeft32(h, -17) * cityC1
+	h = bits.RotateLeft32(h+f, -19)*5 + 0xe6546b64
+	h = bits.RotateLeft32(h, -17) * cityC1
+	return h
+}
+
+func farmHashLen33to64(s []byte) uint64 {
+	n := len(s)
+	mul := cityK2 + uint64(n)*2
+	a := read64(s, 0) * cityK2
+	b := read64(s, 8)
+	c := read64(s, n-8) * mul
+	d := read64(s, n-16) * cityK2
+	y := bits.RotateLeft64(a+b, -43) + bits.RotateLeft64(c, -30) + d
+	z := cityHashLen16Mul(y, a+bits.RotateLeft64(b+cityK2, -18)+c, mul)
+	e := read64(s, 16) * mul
+	f := read64(s, 24)
+	g := (y + read64(s, n-32)) * mul
+	h := (z + read64(s, n-24)) * mul
+	return cityHashLen16Mul(bits.RotateLeft64(e+f, -43)+bits.RotateLeft64(g, -30)+h,
+		e+bits.RotateLeft64(f+a, -18)+g, mul)
+}
+
+func farmHash32Len13to24(s []byte) uint32 {
+	n := len(s)
+	a := read32(s, (n>>1)-4)
+	b := read32(s, 4)
+	c := read32(s, n-8)
+	d := read32(s, n>>1)
+	e := read32(s, 0)
+	f := read32(s, n-4)
+	h := d*cityC1 + uint32(n)
+	a = bits.RotateLeft32(a, -12) + f
+	h = cityMur(c, h) + a
+	a = bits.RotateLeft32(a, -3) + c
+	h = cityMur(e, h) + a
+	a = bits.RotateLeft32(a+f, -12) + d
+	h = cityMur(b, h) + a
+	return cityFmix(h)
+}
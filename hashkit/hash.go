@@ -24,6 +24,24 @@ func Murmur64(data []byte) uint64 {
 	return murmur3.Sum64WithSeed(data, seed)
 }
 
+// Murmur32WithSeed returns Murmur3 with a fixed seed, unlike Murmur32 whose seed depends
+// only on the data length. A random seed, e.g. RandomSeed, makes the hashes differ across
+// processes but seed-independent collisions of Murmur3 are known, use SipHash32 when
+// the keys may be chosen by an attacker.
+func Murmur32WithSeed(seed uint32) HashFunc32 {
+	return func(data []byte) uint32 {
+		return murmur3.Sum32WithSeed(data, seed)
+	}
+}
+
+// Murmur64WithSeed returns the 64 bits Murmur3 with a fixed seed, Murmur3 seeds only have
+// 32 bits.
+func Murmur64WithSeed(seed uint32) HashFunc64 {
+	return func(data []byte) uint64 {
+		return murmur3.Sum64WithSeed(data, seed)
+	}
+}
+
 func Fnv32(data []byte) uint32 {
 	f := fnv.New32()
 	f.Write(data)
@@ -37,9 +55,7 @@ func Fnv64(data []byte) uint64 {
 }
 
 func Md5(key []byte) uint32 {
-	m := md5.New()
-	m.Write(key)
-	results := m.Sum(nil)
+	results := md5.Sum(key)
 	return (uint32(results[3]&0xFF) << 24) | (uint32(results[2]&0xFF) << 16) |
 		(uint32(results[1]&0xFF) << 8) | (uint32(results[0]) & 0xFF)
 }
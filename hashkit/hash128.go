@@ -0,0 +1,53 @@
+package hashkit
+
+import "github.com/spaolacci/murmur3"
+
+// HashFunc128 returns the two 64 bits halves of a 128 bits hash, such as the two
+// independent hashes of double hashing.
+type HashFunc128 func([]byte) (uint64, uint64)
+
+type StringHashFunc128 func(string) (uint64, uint64)
+
+// Murmur128 is Murmur3 x64_128 seeded like Murmur32 and Murmur64, its first half is Murmur64.
+func Murmur128(data []byte) (uint64, uint64) {
+	var ukLen = uint32(len(data))
+	var seed = 0xdeadbeef * ukLen
+
+	return murmur3.Sum128WithSeed(data, seed)
+}
+
+// Murmur128WithSeed returns Murmur3 x64_128 with a fixed seed.
+func Murmur128WithSeed(seed uint32) HashFunc128 {
+	return func(data []byte) (uint64, uint64) {
+		return murmur3.Sum128WithSeed(data, seed)
+	}
+}
+
+// XXH128WithSeed returns the 128 bits XXH3 with seed.
+func XXH128WithSeed(seed uint64) HashFunc128 {
+	return func(data []byte) (uint64, uint64) {
+		return xxh3x128(data, seed)
+	}
+}
+
+// String128 adapts h to strings without copying them, like String32.
+func String128(h HashFunc128) StringHashFunc128 {
+	return func(s string) (uint64, uint64) {
+		return h(unsafeBytes(s))
+	}
+}
+
+// Indexes appends to dst k indexes in [0, n) derived from the halves h1 and h2 of a
+// 128 bits hash by enhanced double hashing, see Dillinger and Manolios, "Bloom Filters
+// in Probabilistic Verification". The indexes behave like k independent hashes for
+// filters and sketches, and unlike plain double hashing do not collapse to a single
+// index when h2 is a multiple of n. Reusing dst makes it allocation free.
+func Indexes(dst []uint64, h1, h2 uint64, k int, n uint64) []uint64 {
+	x, y := h1%n, h2%n
+	for i := 0; i < k; i++ {
+		dst = append(dst, x)
+		x = (x + y) % n
+		y = (y + uint64(i) + 1) % n
+	}
+	return dst
+}
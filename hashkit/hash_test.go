@@ -0,0 +1,318 @@
+package hashkit
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// sanityBuffer fills a buffer like the sanity check of xxHash.
+func sanityBuffer(n int) []byte {
+	buf := make([]byte, n)
+	gen := uint64(2654435761)
+	for i := range buf {
+		buf[i] = byte(gen >> 56)
+		gen *= 11400714785074694797
+	}
+	return buf
+}
+
+// hashVectors are the hashes of the prefixes of the sanity buffer, computed with the
+// reference implementations, the lengths cover every branch of every hash.
+var hashVectors = []struct {
+	n        int
+	xxh64    uint64
+	xxh3     uint64
+	xxh128hi uint64
+	xxh128lo uint64
+	city64   uint64
+	farm64   uint64
+	city32   uint32
+	farm32   uint32
+}{
+	{0, 0xef46db3751d8e999, 0x2d06800538d394c2, 0x99aa06d3014798d8, 0x6001c324468d497f, 0x9ae16a3b2f90404f, 0x9ae16a3b2f90404f, 0xdc56d17a, 0xdc56d17a},
+	{1, 0xe934a84adb052768, 0xc44bdff4074eecdb, 0xa6cd5e9392000f6a, 0xc44bdff4074eecdb, 0xbe6056edf5e94b54, 0xbe6056edf5e94b54, 0xc0a92754, 0xc0a92754},
+	{3, 0xff7e1959cb50794a, 0x54247382a8d6b94d, 0x20efc49ff02422ea, 0x54247382a8d6b94d, 0x7bb6cd1ba35be31c, 0x7bb6cd1ba35be31c, 0x54b2ef7b, 0x54b2ef7b},
+	{4, 0x9136a0dca57457ee, 0xe5dc74bc51848a51, 0x970d585ac632bf8e, 0x2e7d8d6876a39fe9, 0xfe8a8cfa3ce9af68, 0xfe8a8cfa3ce9af68, 0x78f648fb, 0x78f648fb},
+	{8, 0xcdbcf538e71d1348, 0x24ccc9acaa9f65e4, 0x47a7f080d82bb456, 0x64c69cab4bb21dc5, 0x817e3bdc47f9b327, 0x817e3bdc47f9b327, 0xff969e87, 0xff969e87},
+	{9, 0x554b1ae991eda6b6, 0x14d5001c15dd3f2b, 0x564ef6078950d457, 0xed7ccbc501eb7501, 0xaab526d6d2e263ad, 0xaab526d6d2e263ad, 0xae9249f2, 0xae9249f2},
+	{12, 0x0723bf50086ead9a, 0xa713daf0dfbb77e7, 0x6e3efd8fc7802b18, 0x061a192713f69ad9, 0x49c27a776269ea75, 0x49c27a776269ea75, 0x00fc032b, 0x00fc032b},
+	{13, 0xc2e5013e3c40bcf7, 0x2eb03c6e66ba6524, 0x30d4b04dda0e2514, 0x4cb46e6932d0bce2, 0x2105363e5695cb0a, 0x2105363e5695cb0a, 0xc8b67257, 0x5d3a1ae1},
+	{16, 0x98c90b57fdfcb55c, 0x981b17d36c7498c9, 0xc68c368ecf8a9c05, 0x562980258a998629, 0x0f0fcc658b22100f, 0x0f0fcc658b22100f, 0x46c87362, 0x36d1bbee},
+	{17, 0x0d39a2d051a30c2c, 0x796f5acd3a60f862, 0x955fa78643ed3669, 0xabbc12d11973d7db, 0x74e2c0facc28bacd, 0x74e2c0facc28bacd, 0xe7197cff, 0xa3919caf},
+	{24, 0xf75a6dea42dc5bf4, 0xa3fe70bf9d3510eb, 0x0ce966e4678d3761, 0x1e7044d28b1b901d, 0x99ad9fd459d82b9a, 0x99ad9fd459d82b9a, 0x8c8b1c37, 0xae4c0063},
+	{25, 0x52faa43c3f20b994, 0x9e41329ed21a1d6d, 0xa4bdb1865649aed0, 0x77211ae67471b0a7, 0x0668aefac2c85da4, 0x0668aefac2c85da4, 0xe0415c6d, 0x4cd4e8c9},
+	{32, 0x18b216492bb44b70, 0x9feaddbdbf57eed3, 0x98fc6458710dc2e8, 0x278410a17595e3f9, 0xb138b6f869079fa3, 0xb138b6f869079fa3, 0xe364fe5c, 0x89826dc1},
+	{33, 0x55c8dc3e578f5b59, 0xabfb2d081b400a10, 0x3103c192ceaa2ded, 0xe593bc4e5914c9d1, 0xd32e523b9028d9a2, 0x5961d2f5fad1c933, 0x105fd941, 0x4b79e65c},
+	{48, 0xfd0feeac7a939933, 0x397da259ecba1f11, 0xa002ac4e5478227e, 0xf942219aed80f67b, 0x67f5fcecec0941bb, 0xbc5d4be847899c5d, 0x4d02e27c, 0x6787c9cb},
+	{64, 0xef558f8acac2b5cd, 0x9cb48487720ec49d, 0x6d90e81a9b0fd622, 0xefdb6a44690721a9, 0x6e4e70ea1f0bb636, 0x268ae3c5de536058, 0x68562f1c, 0xdb9c00d3},
+	{65, 0xde0f20dc2631af7a, 0xfd81aac4bebc3883, 0x6c074d65e54db85a, 0xfe2f650fa500ec6e, 0xe30d97318ac5ddfb, 0x87e571739dec71a5, 0x6c9a8a04, 0x29b152ed},
+	{96, 0x105064e743edd1d9, 0x935a769a7f94776f, 0xd9d0b885f56c93f1, 0xe9324473ea9afebe, 0x765398a30588fec5, 0x97c7e79654a89414, 0x64517052, 0x8e3ad4c1},
+	{97, 0x097b16e4e9b0a2e3, 0xca4ca268fd3c3a6c, 0x09dff37faa6b284c, 0x7c87228ae9671ba7, 0x33bd27e9070fc35a, 0x702af4ba861e40fb, 0xb18bc16f, 0x5a4bece3},
+	{128, 0x90ca021457d96dc5, 0xfcff24126754d861, 0x39992220e045260a, 0xebb15e34a7fb5ab1, 0x670902ecd55c312f, 0x073970a9f708338c, 0x94fd3c90, 0x559da95d},
+	{129, 0x41c280132d697aba, 0x98f1b0a679a2ca29, 0x03815fc91f1b30b6, 0x86c9e3bc8f0a3b5c, 0x5b1a8d6059343c66, 0x3d87cad54fc9c421, 0x774371d3, 0xd2b8ba6f},
+	{200, 0x4d863378a2052d65, 0xbddca58935d7c038, 0xe76ff4780fe18439, 0xeb060f1bb3126f5a, 0x732ba1aec82a393b, 0xa107925b00acc601, 0xb22ccf55, 0x1dd097f2},
+	{240, 0xb81838d483baee53, 0x81c3c2b67f568ccf, 0xaa4202daa2769dc8, 0x5c9aae94c8ebe5a0, 0x1a6cdb6a661fa8ed, 0x303315e904414461, 0xab6eba16, 0xd5fc2145},
+	{241, 0x95d76c8b4d8fc4d6, 0xc5a639ecd2030e5e, 0x99a80ecf0ecfc647, 0xc5a639ecd2030e5e, 0x7f86e6123d3a4630, 0xbb6fb07d831c408f, 0xfbe4a4db, 0x4e8fccb4},
+	{1024, 0x4775bf7cace4d177, 0xdd85c9b5c1109c5c, 0x0d30d24071c64c57, 0xdd85c9b5c1109c5c, 0x703f4624e5427ed3, 0x885bdd1398360b4f, 0x0611cd55, 0x329eb76c},
+	{1025, 0x847fa6006d7c2ac0, 0xd870c0fa13211c6a, 0xfd3ee4fe7f2954c6, 0xd870c0fa13211c6a, 0x3ecab9479acc28aa, 0x97f7b52e50f5b935, 0x1529219f, 0x3417e619},
+	{2049, 0x3bac0fef077cbfb5, 0xd3afa4329779b921, 0x4cd2bd192f2d70bd, 0xd3afa4329779b921, 0x4c8c96f7f89b1d80, 0x8defec5f55fd43a0, 0x042f9a96, 0x071b5249},
+	{4103, 0xaaafa9b492392879, 0xd6e778c94923ea44, 0x5b449e3abeea1486, 0xd6e778c94923ea44, 0x1365c447ad8a924d, 0x79680046fe08f516, 0x3d7b3d01, 0x4dd6b4ce},
+}
+
+func TestHashVectors(t *testing.T) {
+	buf := sanityBuffer(4103)
+	for _, v := range hashVectors {
+		data := buf[:v.n]
+		assert.Equal(t, v.xxh64, XXHash64(data), "xxh64 %d", v.n)
+		assert.Equal(t, v.xxh3, XXH3(data), "xxh3 %d", v.n)
+		hi, lo := XXH128(data)
+		assert.Equal(t, v.xxh128hi, hi, "xxh128 %d", v.n)
+		assert.Equal(t, v.xxh128lo, lo, "xxh128 %d", v.n)
+		assert.Equal(t, v.city64, City64(data), "city64 %d", v.n)
+		assert.Equal(t, v.farm64, Farm64(data), "farm64 %d", v.n)
+		assert.Equal(t, v.city32, City32(data), "city32 %d", v.n)
+		assert.Equal(t, v.farm32, Farm32(data), "farm32 %d", v.n)
+	}
+}
+
+func TestXXHash64(t *testing.T) {
+	assert.Equal(t, uint64(0xef46db3751d8e999), XXHash64(nil))
+	assert.Equal(t, uint64(0xd24ec4f1a98c6e5b), XXHash64([]byte("a")))
+	assert.Equal(t, uint64(0x44bc2cf5ad770999), XXHash64([]byte("abc")))
+}
+
+func TestWyhash(t *testing.T) {
+	// the test vectors of the reference implementation, hashed with their index as seed
+	vectors := []struct {
+		data string
+		hash uint64
+	}{
+		{"", 0x93228a4de0eec5a2},
+		{"a", 0xc5bac3db178713c4},
+		{"abc", 0xa97f2f7b1d9b3314},
+		{"message digest", 0x786d1f1df3801df4},
+		{"abcdefghijklmnopqrstuvwxyz", 0xdca5a8138ad37c87},
+		{"ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789", 0xb9e734f117cfaf70},
+		{"12345678901234567890123456789012345678901234567890123456789012345678901234567890", 0x6cc5eab49a92d617},
+	}
+	for i, v := range vectors {
+		assert.Equal(t, v.hash, wyhash([]byte(v.data), uint64(i)), v.data)
+	}
+	assert.Equal(t, wyhash([]byte("abc"), 0), Wyhash([]byte("abc")))
+}
+
+func TestSeededHashes(t *testing.T) {
+	buf := sanityBuffer(300)
+	vectors := []struct {
+		n     int
+		xxh64 uint64 // seeded with 2654435761
+		xxh3  uint64 // seeded with 11400714785074694797
+	}{
+		{0, 0xac75fda2929b17ef, 0xa8a6b918b2f0364a},
+		{1, 0x5014607643a9b4c3, 0x032be332dd766ef8},
+		{14, 0xc3bd6bf63deb6df0, 0xa7f68521581b173f},
+		{222, 0x20cb8ab7ae10c14a, 0xcd627e7ca214ebfd},
+		{300, 0x36cb189d9f3b6ad6, 0x1c886c664c4d81c4},
+	}
+	xxh64 := XXHash64WithSeed(2654435761)
+	xxh3 := XXH3WithSeed(11400714785074694797)
+	for _, v := range vectors {
+		assert.Equal(t, v.xxh64, xxh64(buf[:v.n]), "xxh64 %d", v.n)
+		assert.Equal(t, v.xxh3, xxh3(buf[:v.n]), "xxh3 %d", v.n)
+	}
+	assert.Equal(t, XXH3(buf), XXH3WithSeed(0)(buf))
+	assert.Equal(t, Wyhash(buf), WyhashWithSeed(0)(buf))
+
+	data := []byte("hashkit")
+	seed := uint32(0xdeadbeef * len(data))
+	assert.Equal(t, Murmur32(data), Murmur32WithSeed(seed)(data))
+	assert.Equal(t, Murmur64(data), Murmur64WithSeed(seed)(data))
+	assert.NotEqual(t, Murmur32WithSeed(1)(data), Murmur32WithSeed(2)(data))
+}
+
+func TestSipHash(t *testing.T) {
+	// the test vectors of the reference implementation: key 00..0f, message 00..n-1
+	vectors := []struct {
+		n    int
+		hash uint64
+	}{
+		{0, 0x726fdb47dd0e0e31},
+		{1, 0x74f839c593dc67fd},
+		{7, 0xab0200f58b01d137},
+		{8, 0x93f5f5799a932462},
+		{15, 0xa129ca6149be45e5},
+		{16, 0x3f2acc7f57c29bdb},
+		{63, 0x958a324ceb064572},
+	}
+	msg := make([]byte, 64)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+	k0, k1 := uint64(0x0706050403020100), uint64(0x0f0e0d0c0b0a0908)
+	hash, hash32 := SipHash(k0, k1), SipHash32(k0, k1)
+	for _, v := range vectors {
+		assert.Equal(t, v.hash, hash(msg[:v.n]), "siphash %d", v.n)
+		assert.Equal(t, uint32(v.hash), hash32(msg[:v.n]), "siphash32 %d", v.n)
+	}
+	assert.NotEqual(t, RandomSeed(), RandomSeed())
+}
+
+func TestHash128(t *testing.T) {
+	h1, h2 := Murmur128WithSeed(0)([]byte("The quick brown fox jumps over the lazy dog"))
+	assert.Equal(t, uint64(0xe34bbc7bbc071b6c), h1)
+	assert.Equal(t, uint64(0x7a433ca9c49a9347), h2)
+	h1, h2 = XXH128WithSeed(0)([]byte("abc"))
+	assert.Equal(t, uint64(0x06b05ab6733a6185), h1)
+	assert.Equal(t, uint64(0x78af5f94892f3950), h2)
+
+	data := []byte("hashkit")
+	h1, _ = Murmur128(data)
+	assert.Equal(t, Murmur64(data), h1)
+	for _, h := range []HashFunc128{Murmur128, XXH128, XXH128WithSeed(1)} {
+		h1, h2 := h(data)
+		s1, s2 := String128(h)("hashkit")
+		assert.Equal(t, h1, s1)
+		assert.Equal(t, h2, s2)
+	}
+}
+
+func TestIndexes(t *testing.T) {
+	assert.Equal(t, []uint64{3, 8, 4, 2, 3}, Indexes(nil, 13, 5, 5, 10))
+	// plain double hashing would give 1 five times
+	assert.Equal(t, []uint64{1, 1, 2, 5, 1}, Indexes(nil, 1, 10, 5, 10))
+
+	var buf [8]uint64
+	data := []byte("hashkit")
+	assert.Zero(t, testing.AllocsPerRun(100, func() {
+		h1, h2 := Murmur128(data)
+		Indexes(buf[:0], h1, h2, len(buf), 1000)
+	}))
+	for _, idx := range Indexes(buf[:0], 1<<63, 1<<62+1, len(buf), 7) {
+		assert.Less(t, idx, uint64(7))
+	}
+}
+
+func TestTwemproxyHashes(t *testing.T) {
+	// the outputs of the hash functions of twemproxy compiled on x86, with its signed
+	// chars and its unmasked crc16
+	keys := []string{"", "a", "ab", "abc", "hello", "123456789", "foo:bar:baz",
+		"The quick brown fox jumps over the lazy dog", "\xff\x80\x7f\xe4\xb8\xad\xe6\x96\x87",
+		"0123456789ab", "0123456789abcdefghijklmn", "abcd\xe4\xb8\xad"}
+	vectors := map[string][]uint32{
+		"one_at_a_time": {0x00000000, 0xca2e9442, 0x45e61e58, 0xed131f5b, 0xc8fd181b, 0xc66b58c5, 0x7358e4e9, 0x519e91f5, 0x6a8c38aa, 0xf6361f26, 0xe8681d0d, 0x08f15c87},
+		"md5":           {0xd98c1dd4, 0xb975c10c, 0x43f47e18, 0x98500190, 0x2a40415d, 0x94e7f925, 0x09a4f25b, 0x9d7d109e, 0xdb7e9028, 0xbf520cb6, 0xdbce87e7, 0x404c2d6b},
+		"crc16":         {0x00000000, 0x00007c87, 0x007c74ff, 0x7c749dd6, 0x6b4fc362, 0x869031c3, 0x047d73e7, 0x22a3f0c8, 0xe6df4cfd, 0x9c66f284, 0x8fc9a90d, 0xbf38341c},
+		"crc32":         {0x00000000, 0x000068b7, 0x00001e83, 0x00003524, 0x00003610, 0x00004bf4, 0x000020bd, 0x0000414f, 0x00000d96, 0x00000623, 0x00006098, 0x000035d7},
+		"crc32a":        {0x00000000, 0xe8b7be43, 0x9e83486d, 0x352441c2, 0x3610a686, 0xcbf43926, 0x20bd4479, 0x414fa339, 0x0d967169, 0x0623c932, 0x609850ad, 0xb5d7d82e},
+		"fnv1_64":       {0x84222325, 0x8601b7be, 0xb4eb37b8, 0x6bafadcb, 0xbdbdd4c7, 0x2bf916d6, 0x435ef423, 0x7de37ace, 0x1c2dba9f, 0xa4cfc71d, 0x73970529, 0x94244ba6},
+		"fnv1a_64":      {0x84222325, 0x8601ec8c, 0xb545986a, 0x0541574b, 0x80aabd0b, 0x23c6cdfc, 0xfa2713e7, 0xe7e47110, 0x2c87501b, 0x43b49151, 0x7226c9ed, 0x9e78a27c},
+		"fnv1_32":       {0x811c9dc5, 0x050c5d7e, 0x70772d38, 0x439c2f4b, 0xb6fa7167, 0x24148816, 0x578d7e03, 0xe9c86c6e, 0xa596147f, 0x1963825d, 0xeeb3ed29, 0x51eced26},
+		"fnv1a_32":      {0x811c9dc5, 0xe40c292c, 0x4d2505ca, 0x1a47e90b, 0x4f9f2cab, 0xbb86b11c, 0xe08b1ac7, 0x048fff90, 0x368d383b, 0x2d4c5d51, 0xe90ac42d, 0xc129d5dc},
+		"hsieh":         {0x00000000, 0x93642e87, 0x5b8c0ec3, 0xe5186b3a, 0x13842ac5, 0xe4fc1670, 0x70b9f58a, 0x1c19ee97, 0x921d47f7, 0xabe296d7, 0xb661aa02, 0x3321ce08},
+		"murmur":        {0x00000000, 0x4b41757c, 0xe3b54dfb, 0x7b0cc428, 0x521b0bff, 0xb7760690, 0xac67de49, 0x30c79125, 0x4ea11e1e, 0x0d4e2821, 0x7f539687, 0x1bb379ae},
+		"jenkins":       {0xdeadbefc, 0xe0a38690, 0xc1b5695b, 0x8f415600, 0x2e0cc8f3, 0x19777af6, 0xe2d820d8, 0x12b8163c, 0x647d1502, 0x6d223753, 0x98c99d58, 0xee6937ca},
+	}
+	for name, hashes := range vectors {
+		h, err := Lookup32(name)
+		assert.NoError(t, err)
+		for i, key := range keys {
+			assert.Equal(t, hashes[i], h([]byte(key)), "%s %q", name, key)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	h, err := Lookup32("fnv1a_64")
+	assert.NoError(t, err)
+	assert.Equal(t, Fnv1a_64([]byte("abc")), h([]byte("abc")))
+	_, err = Lookup32("xxh3")
+	assert.Error(t, err)
+	h64, err := Lookup64("xxh3")
+	assert.NoError(t, err)
+	assert.Equal(t, XXH3([]byte("abc")), h64([]byte("abc")))
+	_, err = Lookup64("unknown")
+	assert.Error(t, err)
+
+	assert.Contains(t, Names32(), "one_at_a_time")
+	assert.Contains(t, Names64(), "wyhash")
+	assert.IsIncreasing(t, Names32())
+}
+
+func TestStringHash(t *testing.T) {
+	key := "test_key_123456789"
+	for _, h := range []HashFunc32{Murmur32, Fnv32, Md5, City32, Farm32} {
+		hs := String32(h)
+		assert.Equal(t, h([]byte(key)), hs(key))
+		assert.Zero(t, testing.AllocsPerRun(100, func() { hs(key) }))
+	}
+	for _, h := range []HashFunc64{Murmur64, Fnv64, XXHash64, XXH3, Wyhash, City64, Farm64, SipHash(1, 2)} {
+		hs := String64(h)
+		assert.Equal(t, h([]byte(key)), hs(key))
+		assert.Zero(t, testing.AllocsPerRun(100, func() { hs(key) }))
+	}
+}
+
+func TestStreamingHash(t *testing.T) {
+	h32, h64 := NewHash32(Murmur32), NewHash64(XXH3)
+	num := []byte("12")
+	write := func(w interface {
+		Write([]byte) (int, error)
+		WriteString(string) (int, error)
+		Reset()
+	}) {
+		w.Reset()
+		w.Write(num)
+		w.WriteString("test0.github.com")
+	}
+	write(h32)
+	assert.Equal(t, Murmur32([]byte("12test0.github.com")), h32.Sum32())
+	write(h64)
+	assert.Equal(t, XXH3([]byte("12test0.github.com")), h64.Sum64())
+
+	assert.Zero(t, testing.AllocsPerRun(100, func() {
+		write(h32)
+		h32.Sum32()
+	}))
+}
+
+var benchSizes = []int{8, 32, 256, 4096}
+
+func benchmarkHash64(b *testing.B, hash HashFunc64) {
+	for _, n := range benchSizes {
+		data := sanityBuffer(n)
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			b.SetBytes(int64(n))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				hash(data)
+			}
+		})
+	}
+}
+
+func benchmarkHash32(b *testing.B, hash HashFunc32) {
+	for _, n := range benchSizes {
+		data := sanityBuffer(n)
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			b.SetBytes(int64(n))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				hash(data)
+			}
+		})
+	}
+}
+
+func BenchmarkMurmur64(b *testing.B) { benchmarkHash64(b, Murmur64) }
+func BenchmarkXXHash64(b *testing.B) { benchmarkHash64(b, XXHash64) }
+func BenchmarkXXH3(b *testing.B)     { benchmarkHash64(b, XXH3) }
+func BenchmarkWyhash(b *testing.B)   { benchmarkHash64(b, Wyhash) }
+func BenchmarkCity64(b *testing.B)   { benchmarkHash64(b, City64) }
+func BenchmarkFarm64(b *testing.B)   { benchmarkHash64(b, Farm64) }
+func BenchmarkSipHash(b *testing.B)  { benchmarkHash64(b, SipHash(RandomSeed(), RandomSeed())) }
+func BenchmarkMurmur32(b *testing.B) { benchmarkHash32(b, Murmur32) }
+func BenchmarkMd5(b *testing.B)      { benchmarkHash32(b, Md5) }
+func BenchmarkCity32(b *testing.B)   { benchmarkHash32(b, City32) }
+func BenchmarkFarm32(b *testing.B)   { benchmarkHash32(b, Farm32) }
@@ -0,0 +1,370 @@
+package quality
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"math/rand"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/zjbztianya/go-misc/hashkit"
+)
+
+const (
+	DefaultKeys    = 100000
+	DefaultSamples = 10000
+	DefaultBuckets = 4096
+
+	// sigmas bound the statistics of a good hash, they are the limits of the
+	// avalanche and independence tests and of the chi-square z-score.
+	sigmas = 6
+)
+
+// Config tunes the size of the tests, the defaults take a few seconds per hash.
+type Config struct {
+	Keys    int   // keys of the distribution and collision tests, DefaultKeys if 0
+	Samples int   // random keys of the avalanche and independence tests, DefaultSamples if 0
+	Buckets int   // buckets of the chi-square test, a power of 2, DefaultBuckets if 0
+	Seed    int64 // of the random keys, the same seed gives the same report
+}
+
+// Result is the outcome of a test: the worst statistic measured and its limit.
+type Result struct {
+	Test   string
+	Keys   string
+	Value  float64
+	Limit  float64
+	Pass   bool
+	Detail string
+}
+
+// Report is the outcome of all the tests of a hash.
+type Report struct {
+	Hash    string
+	Bits    int
+	Results []Result
+}
+
+// Passed reports whether the hash passed every test.
+func (r *Report) Passed() bool {
+	return r.Failures() == 0
+}
+
+func (r *Report) Failures() int {
+	var n int
+	for _, res := range r.Results {
+		if !res.Pass {
+			n++
+		}
+	}
+	return n
+}
+
+// WriteTo writes the report as a table.
+func (r *Report) WriteTo(w io.Writer) (int64, error) {
+	cw := &countWriter{w: w}
+	fmt.Fprintf(cw, "%s (%d bits)\n", r.Hash, r.Bits)
+	tw := tabwriter.NewWriter(cw, 0, 8, 2, ' ', 0)
+	for _, res := range r.Results {
+		status := "ok"
+		if !res.Pass {
+			status = "FAIL"
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%.4g\t(limit %.4g)\t%s\t%s\n", res.Test, res.Keys, res.Value, res.Limit, status, res.Detail)
+	}
+	tw.Flush()
+	if r.Passed() {
+		fmt.Fprintf(cw, "  PASS\n")
+	} else {
+		fmt.Fprintf(cw, "  FAIL: %d of %d tests\n", r.Failures(), len(r.Results))
+	}
+	return cw.n, cw.err
+}
+
+type countWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
+
+// hasher is a hash of 32 or 64 bits, 32 bits hashes are in the low bits.
+type hasher struct {
+	bits int
+	fn   func([]byte) uint64
+}
+
+// Test32 runs every test against a 32 bits hash.
+func Test32(name string, h hashkit.HashFunc32, c *Config) *Report {
+	return run(name, hasher{bits: 32, fn: func(b []byte) uint64 { return uint64(h(b)) }}, c)
+}
+
+// Test64 runs every test against a 64 bits hash.
+func Test64(name string, h hashkit.HashFunc64, c *Config) *Report {
+	return run(name, hasher{bits: 64, fn: h}, c)
+}
+
+func run(name string, h hasher, c *Config) *Report {
+	cfg := Config{Keys: DefaultKeys, Samples: DefaultSamples, Buckets: DefaultBuckets}
+	if c != nil {
+		cfg.Seed = c.Seed
+		if c.Keys > 0 {
+			cfg.Keys = c.Keys
+		}
+		if c.Samples > 0 {
+			cfg.Samples = c.Samples
+		}
+		if c.Buckets > 0 {
+			cfg.Buckets = c.Buckets
+		}
+	}
+	if cfg.Buckets&(cfg.Buckets-1) != 0 {
+		panic("quality buckets must be a power of 2")
+	}
+	r := rand.New(rand.NewSource(cfg.Seed))
+	report := &Report{Hash: name, Bits: h.bits}
+	for _, size := range []int{4, 8, 16, 64} {
+		report.Results = append(report.Results, avalanche(h, randomKeys(r, cfg.Samples, size)))
+	}
+	report.Results = append(report.Results, independence(h, randomKeys(r, cfg.Samples, 8)))
+	for _, ks := range []keySet{
+		{"random", randomKeys(r, cfg.Keys, 16)},
+		{"sequential", sequentialKeys(cfg.Keys)},
+		{"text", textKeys(cfg.Keys)},
+		{"sparse", sparseKeys()},
+	} {
+		report.Results = append(report.Results, distribution(h, ks, cfg.Buckets), collisions(h, ks))
+	}
+	return report
+}
+
+type keySet struct {
+	name string
+	keys [][]byte
+}
+
+func randomKeys(r *rand.Rand, n, size int) [][]byte {
+	keys := make([][]byte, n)
+	for i := range keys {
+		keys[i] = make([]byte, size)
+		r.Read(keys[i])
+	}
+	return keys
+}
+
+// sequentialKeys returns 0 to n-1 as 8 bytes little endian integers.
+func sequentialKeys(n int) [][]byte {
+	keys := make([][]byte, n)
+	for i := range keys {
+		keys[i] = make([]byte, 8)
+		binary.LittleEndian.PutUint64(keys[i], uint64(i))
+	}
+	return keys
+}
+
+// textKeys returns the strings key:0 to key:n-1.
+func textKeys(n int) [][]byte {
+	keys := make([][]byte, n)
+	for i := range keys {
+		keys[i] = strconv.AppendInt([]byte("key:"), int64(i), 10)
+	}
+	return keys
+}
+
+// sparseKeys returns the 43745 8 bytes keys with at most 3 bits set.
+func sparseKeys() [][]byte {
+	var keys [][]byte
+	add := func(v uint64) {
+		k := make([]byte, 8)
+		binary.LittleEndian.PutUint64(k, v)
+		keys = append(keys, k)
+	}
+	add(0)
+	for i := 0; i < 64; i++ {
+		add(1 << i)
+		for j := i + 1; j < 64; j++ {
+			add(1<<i | 1<<j)
+			for k := j + 1; k < 64; k++ {
+				add(1<<i | 1<<j | 1<<k)
+			}
+		}
+	}
+	return keys
+}
+
+// avalanche flips every input bit of the keys and measures how often every output
+// bit flips, it should be half of the time: the strict avalanche criterion. The value
+// is the worst bias, |2p-1|.
+func avalanche(h hasher, keys [][]byte) Result {
+	inBits := len(keys[0]) * 8
+	flips := make([]int, inBits*h.bits)
+	for _, k := range keys {
+		x := h.fn(k)
+		for i := 0; i < inBits; i++ {
+			k[i/8] ^= 1 << (i % 8)
+			d := x ^ h.fn(k)
+			k[i/8] ^= 1 << (i % 8)
+			for ; d != 0; d &= d - 1 {
+				flips[i*h.bits+bits.TrailingZeros64(d)]++
+			}
+		}
+	}
+	worst, at := 0.0, 0
+	for i, f := range flips {
+		if bias := math.Abs(2*float64(f)/float64(len(keys)) - 1); bias > worst {
+			worst, at = bias, i
+		}
+	}
+	limit := sigmas / math.Sqrt(float64(len(keys)))
+	return Result{
+		Test:   "avalanche",
+		Keys:   fmt.Sprintf("%d bytes", len(keys[0])),
+		Value:  worst,
+		Limit:  limit,
+		Pass:   worst <= limit,
+		Detail: fmt.Sprintf("worst bias %.2f%% flipping input bit %d, output bit %d", worst*100, at/h.bits, at%h.bits),
+	}
+}
+
+// independence flips every input bit of the keys and measures the correlation of
+// the flips of every pair of output bits, it should be 0: the bit independence
+// criterion. The value is the worst absolute correlation.
+func independence(h hasher, keys [][]byte) Result {
+	inBits := len(keys[0]) * 8
+	flips := make([]int, inBits*h.bits)
+	pairs := make([]int, inBits*h.bits*h.bits) // both output bits flipped
+	var set [64]int
+	for _, k := range keys {
+		x := h.fn(k)
+		for i := 0; i < inBits; i++ {
+			k[i/8] ^= 1 << (i % 8)
+			d := x ^ h.fn(k)
+			k[i/8] ^= 1 << (i % 8)
+			n := 0
+			for ; d != 0; d &= d - 1 {
+				set[n] = bits.TrailingZeros64(d)
+				n++
+			}
+			row := pairs[i*h.bits*h.bits:]
+			for a := 0; a < n; a++ {
+				flips[i*h.bits+set[a]]++
+				for b := a + 1; b < n; b++ {
+					row[set[a]*h.bits+set[b]]++
+				}
+			}
+		}
+	}
+	n := float64(len(keys))
+	worst, at := 0.0, [3]int{}
+	for i := 0; i < inBits; i++ {
+		for a := 0; a < h.bits; a++ {
+			for b := a + 1; b < h.bits; b++ {
+				pa := float64(flips[i*h.bits+a]) / n
+				pb := float64(flips[i*h.bits+b]) / n
+				pab := float64(pairs[(i*h.bits+a)*h.bits+b]) / n
+				v := pa * (1 - pa) * pb * (1 - pb)
+				if v == 0 {
+					// a bit always or never flipping fails the avalanche test
+					continue
+				}
+				if corr := math.Abs(pab-pa*pb) / math.Sqrt(v); corr > worst {
+					worst, at = corr, [3]int{i, a, b}
+				}
+			}
+		}
+	}
+	limit := sigmas / math.Sqrt(n)
+	return Result{
+		Test:   "independence",
+		Keys:   fmt.Sprintf("%d bytes", len(keys[0])),
+		Value:  worst,
+		Limit:  limit,
+		Pass:   worst <= limit,
+		Detail: fmt.Sprintf("worst correlation %.3f flipping input bit %d, output bits %d and %d", worst, at[0], at[1], at[2]),
+	}
+}
+
+// distribution hashes the keys into buckets by the low and the high bits of the
+// hashes and computes the chi-square statistic of the bucket counts. The value is
+// the worst z-score of the statistic, too uniform distributions are not penalized.
+func distribution(h hasher, ks keySet, buckets int) Result {
+	shift := uint(h.bits - bits.TrailingZeros(uint(buckets)))
+	low := make([]int, buckets)
+	high := make([]int, buckets)
+	for _, k := range ks.keys {
+		x := h.fn(k)
+		low[x&uint64(buckets-1)]++
+		high[x>>shift&uint64(buckets-1)]++
+	}
+	zl, zh := chiSquareZ(low, len(ks.keys)), chiSquareZ(high, len(ks.keys))
+	return Result{
+		Test:   "distribution",
+		Keys:   ks.name,
+		Value:  math.Max(zl, zh),
+		Limit:  sigmas,
+		Pass:   math.Max(zl, zh) <= sigmas,
+		Detail: fmt.Sprintf("chi-square z-score %.2f by low bits, %.2f by high bits, %d buckets", zl, zh, buckets),
+	}
+}
+
+// chiSquareZ returns the z-score of the chi-square statistic of counts against the
+// uniform distribution, the statistic has mean k-1 and variance 2(k-1).
+func chiSquareZ(counts []int, n int) float64 {
+	expected := float64(n) / float64(len(counts))
+	var chi2 float64
+	for _, c := range counts {
+		d := float64(c) - expected
+		chi2 += d * d / expected
+	}
+	df := float64(len(counts) - 1)
+	return (chi2 - df) / math.Sqrt(2*df)
+}
+
+// collisions counts the keys hashed to the value of a previous key, for 64 bits
+// hashes also in the low and the high 32 bits. The value is the collisions of the whole
+// hash, the limit is the number expected plus sigmas standard deviations of a random hash.
+func collisions(h hasher, ks keySet) Result {
+	count := func(f func(uint64) uint64) int {
+		seen := make(map[uint64]struct{}, len(ks.keys))
+		var n int
+		for _, k := range ks.keys {
+			x := f(h.fn(k))
+			if _, ok := seen[x]; ok {
+				n++
+			}
+			seen[x] = struct{}{}
+		}
+		return n
+	}
+	limit := func(bits int) float64 {
+		// the number of collisions is nearly Poisson distributed
+		n := float64(len(ks.keys))
+		expected := n * (n - 1) / 2 / math.Pow(2, float64(bits))
+		return math.Floor(expected + sigmas*math.Sqrt(expected) + 1)
+	}
+
+	full := count(func(x uint64) uint64 { return x })
+	res := Result{Test: "collisions", Keys: ks.name, Value: float64(full), Limit: limit(h.bits)}
+	res.Detail = fmt.Sprintf("%d of %d bits", full, h.bits)
+	res.Pass = res.Value <= res.Limit
+	if h.bits == 64 {
+		l32 := limit(32)
+		lo := count(func(x uint64) uint64 { return x & math.MaxUint32 })
+		hi := count(func(x uint64) uint64 { return x >> 32 })
+		res.Detail += fmt.Sprintf(", %d of low 32 bits, %d of high 32 bits (limit %.4g)", lo, hi, l32)
+		res.Pass = res.Pass && float64(lo) <= l32 && float64(hi) <= l32
+	}
+	return res
+}
@@ -0,0 +1,61 @@
+package quality
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zjbztianya/go-misc/hashkit"
+)
+
+var testConfig = &Config{Keys: 20000, Samples: 2000, Buckets: 256, Seed: 1}
+
+func TestGoodHashes(t *testing.T) {
+	assert.True(t, Test32("murmur32", hashkit.Murmur32, testConfig).Passed())
+	assert.True(t, Test64("wyhash", hashkit.Wyhash, testConfig).Passed())
+	assert.True(t, Test64("siphash", hashkit.SipHash(1, 2), testConfig).Passed())
+}
+
+func TestBadHashes(t *testing.T) {
+	// FNV-1 barely mixes the last byte
+	r := Test32("fnv32", hashkit.Fnv32, testConfig)
+	assert.False(t, r.Passed())
+	assert.False(t, r.Results[0].Pass)
+
+	// the first 4 bytes collide on longer keys and do not avalanche, the output bits
+	// never flip together so the independence test has nothing to correlate
+	prefix := func(b []byte) uint32 {
+		var buf [4]byte
+		copy(buf[:], b)
+		return binary.LittleEndian.Uint32(buf[:])
+	}
+	r = Test32("prefix", prefix, testConfig)
+	for _, res := range r.Results {
+		switch {
+		case res.Test == "avalanche":
+			assert.False(t, res.Pass, res.Test)
+		case res.Test == "collisions" && res.Keys == "random":
+			assert.True(t, res.Pass)
+		case res.Test == "collisions" && res.Keys == "sparse":
+			assert.False(t, res.Pass)
+		}
+	}
+}
+
+func TestSparseKeys(t *testing.T) {
+	assert.Len(t, sparseKeys(), 43745)
+}
+
+func TestReportWriteTo(t *testing.T) {
+	r := &Report{Hash: "h", Bits: 32, Results: []Result{
+		{Test: "avalanche", Keys: "4 bytes", Value: 0.01, Limit: 0.02, Pass: true},
+		{Test: "collisions", Keys: "sparse", Value: 10, Limit: 2},
+	}}
+	var buf bytes.Buffer
+	n, err := r.WriteTo(&buf)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(buf.Len()), n)
+	assert.Contains(t, buf.String(), "h (32 bits)\n")
+	assert.Contains(t, buf.String(), "FAIL: 1 of 2 tests\n")
+}
@@ -0,0 +1,74 @@
+package hashkit
+
+import (
+	"fmt"
+	"sort"
+)
+
+var hashes32 = map[string]HashFunc32{
+	// twemproxy
+	"one_at_a_time": OneAtATime,
+	"md5":           Md5,
+	"crc16":         Crc16,
+	"crc32":         Crc32,
+	"crc32a":        Crc32a,
+	"fnv1_64":       Fnv1_64,
+	"fnv1a_64":      Fnv1a_64,
+	"fnv1_32":       Fnv1_32,
+	"fnv1a_32":      Fnv1a_32,
+	"hsieh":         Hsieh,
+	"murmur":        Murmur2,
+	"jenkins":       Jenkins,
+
+	"murmur32": Murmur32,
+	"fnv32":    Fnv32,
+	"city32":   City32,
+	"farm32":   Farm32,
+}
+
+var hashes64 = map[string]HashFunc64{
+	"murmur64": Murmur64,
+	"fnv64":    Fnv64,
+	"xxh64":    XXHash64,
+	"xxh3":     XXH3,
+	"wyhash":   Wyhash,
+	"city64":   City64,
+	"farm64":   Farm64,
+}
+
+// Lookup32 returns the 32 bits hash named name, such as the twemproxy hash names, e.g.
+// to configure consistenthash.WithHashFunc from a file.
+func Lookup32(name string) (HashFunc32, error) {
+	if h, ok := hashes32[name]; ok {
+		return h, nil
+	}
+	return nil, fmt.Errorf("hashkit: unknown 32 bits hash %q", name)
+}
+
+// Lookup64 returns the 64 bits hash named name.
+func Lookup64(name string) (HashFunc64, error) {
+	if h, ok := hashes64[name]; ok {
+		return h, nil
+	}
+	return nil, fmt.Errorf("hashkit: unknown 64 bits hash %q", name)
+}
+
+// Names32 returns the sorted names of Lookup32.
+func Names32() []string {
+	names := make([]string, 0, len(hashes32))
+	for name := range hashes32 {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Names64 returns the sorted names of Lookup64.
+func Names64() []string {
+	names := make([]string, 0, len(hashes64))
+	for name := range hashes64 {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
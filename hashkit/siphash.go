@@ -0,0 +1,82 @@
+package hashkit
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"math/bits"
+)
+
+// SipHash returns SipHash-2-4 keyed with k0 and k1, the little endian halves of the 128
+// bits key, see Aumasson and Bernstein, "SipHash: a fast short-input PRF".
+// Unlike seeded non-cryptographic hashes, colliding keys can not be crafted without
+// knowing the key, so it resists hash-flooding when the key is secret, e.g. RandomSeed.
+func SipHash(k0, k1 uint64) HashFunc64 {
+	return func(data []byte) uint64 {
+		return siphash(k0, k1, data)
+	}
+}
+
+// SipHash32 returns the low 32 bits of SipHash-2-4 keyed with k0 and k1.
+func SipHash32(k0, k1 uint64) HashFunc32 {
+	return func(data []byte) uint32 {
+		return uint32(siphash(k0, k1, data))
+	}
+}
+
+// RandomSeed returns a seed or key half from crypto/rand, it panics if no randomness
+// is available.
+func RandomSeed() uint64 {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic("hashkit: reading random seed: " + err.Error())
+	}
+	return binary.LittleEndian.Uint64(b[:])
+}
+
+func siphash(k0, k1 uint64, data []byte) uint64 {
+	v0 := k0 ^ 0x736f6d6570736575
+	v1 := k1 ^ 0x646f72616e646f6d
+	v2 := k0 ^ 0x6c7967656e657261
+	v3 := k1 ^ 0x7465646279746573
+
+	round := func() {
+		v0 += v1
+		v1 = bits.RotateLeft64(v1, 13)
+		v1 ^= v0
+		v0 = bits.RotateLeft64(v0, 32)
+		v2 += v3
+		v3 = bits.RotateLeft64(v3, 16)
+		v3 ^= v2
+		v0 += v3
+		v3 = bits.RotateLeft64(v3, 21)
+		v3 ^= v0
+		v2 += v1
+		v1 = bits.RotateLeft64(v1, 17)
+		v1 ^= v2
+		v2 = bits.RotateLeft64(v2, 32)
+	}
+	compress := func(m uint64) {
+		v3 ^= m
+		round()
+		round()
+		v0 ^= m
+	}
+
+	n := len(data)
+	for ; len(data) >= 8; data = data[8:] {
+		compress(binary.LittleEndian.Uint64(data))
+	}
+	// the last block holds the remaining bytes and the length in its high byte
+	last := uint64(n) << 56
+	for i, c := range data {
+		last |= uint64(c) << (8 * i)
+	}
+	compress(last)
+
+	v2 ^= 0xff
+	round()
+	round()
+	round()
+	round()
+	return v0 ^ v1 ^ v2 ^ v3
+}
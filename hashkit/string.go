@@ -0,0 +1,95 @@
+package hashkit
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+type StringHashFunc32 func(string) uint32
+type StringHashFunc64 func(string) uint64
+
+// String32 adapts h to strings without copying them, h must neither modify nor retain
+// its input, which all the hashes of hashkit satisfy.
+func String32(h HashFunc32) StringHashFunc32 {
+	return func(s string) uint32 {
+		return h(unsafeBytes(s))
+	}
+}
+
+// String64 adapts h to strings without copying them, like String32.
+func String64(h HashFunc64) StringHashFunc64 {
+	return func(s string) uint64 {
+		return h(unsafeBytes(s))
+	}
+}
+
+// unsafeBytes returns the bytes of s without copying them, they must not be modified.
+func unsafeBytes(s string) []byte {
+	var b []byte
+	sh := (*reflect.StringHeader)(unsafe.Pointer(&s))
+	bh := (*reflect.SliceHeader)(unsafe.Pointer(&b))
+	bh.Data, bh.Len, bh.Cap = sh.Data, sh.Len, sh.Len
+	return b
+}
+
+// Hash32 hashes a key written in several parts, such as a composite key, as its hash
+// function hashes their concatenation. The parts are buffered and the buffer is kept
+// across resets, so hashing many keys only allocates while the buffer grows.
+type Hash32 interface {
+	Write(p []byte) (int, error)
+	WriteString(s string) (int, error)
+	Reset()
+	Sum32() uint32
+}
+
+// Hash64 is the 64 bits Hash32.
+type Hash64 interface {
+	Write(p []byte) (int, error)
+	WriteString(s string) (int, error)
+	Reset()
+	Sum64() uint64
+}
+
+type keyBuffer struct {
+	buf []byte
+}
+
+func (k *keyBuffer) Write(p []byte) (int, error) {
+	k.buf = append(k.buf, p...)
+	return len(p), nil
+}
+
+func (k *keyBuffer) WriteString(s string) (int, error) {
+	k.buf = append(k.buf, s...)
+	return len(s), nil
+}
+
+func (k *keyBuffer) Reset() {
+	k.buf = k.buf[:0]
+}
+
+type hash32 struct {
+	keyBuffer
+	h HashFunc32
+}
+
+func NewHash32(h HashFunc32) Hash32 {
+	return &hash32{h: h}
+}
+
+func (h *hash32) Sum32() uint32 {
+	return h.h(h.buf)
+}
+
+type hash64 struct {
+	keyBuffer
+	h HashFunc64
+}
+
+func NewHash64(h HashFunc64) Hash64 {
+	return &hash64{h: h}
+}
+
+func (h *hash64) Sum64() uint64 {
+	return h.h(h.buf)
+}
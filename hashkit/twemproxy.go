@@ -0,0 +1,230 @@
+package hashkit
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"math/bits"
+)
+
+// The hashes of twemproxy, named after its hash configuration values. Like twemproxy on
+// x86 some of them sign extend the bytes of the key as signed chars, which changes their
+// outputs from the reference algorithms only for keys with bytes from 0x80. md5 is Md5.
+
+const (
+	fnv32Offset = 2166136261
+	fnv32Prime  = 16777619
+	fnv64Offset = 0xcbf29ce484222325
+	fnv64Prime  = 0x100000001b3
+)
+
+// signed returns c sign extended like a signed char.
+func signed(c byte) uint32 {
+	return uint32(int8(c))
+}
+
+// OneAtATime is one_at_a_time, the one-at-a-time hash of Bob Jenkins.
+func OneAtATime(data []byte) uint32 {
+	var h uint32
+	for _, c := range data {
+		h += signed(c)
+		h += h << 10
+		h ^= h >> 6
+	}
+	h += h << 3
+	h ^= h >> 11
+	h += h << 15
+	return h
+}
+
+// Crc16 is crc16. Its low 16 bits are the CRC-16/XMODEM of the key but like twemproxy
+// the CRC is kept in 32 bits and never masked, so the bits shifted out stay in the hash.
+func Crc16(data []byte) uint32 {
+	var crc uint32
+	for _, c := range data {
+		crc = crc<<8 ^ uint32(crc16Table[byte(crc>>8)^c])
+	}
+	return crc
+}
+
+// Crc32 is crc32, bits 16 to 30 of the CRC-32 of the key like libmemcached.
+func Crc32(data []byte) uint32 {
+	return crc32.ChecksumIEEE(data) >> 16 & 0x7fff
+}
+
+// Crc32a is crc32a, the CRC-32 of the key.
+func Crc32a(data []byte) uint32 {
+	return crc32.ChecksumIEEE(data)
+}
+
+// Fnv1_64 is fnv1_64, the low 32 bits of the 64 bits FNV-1.
+func Fnv1_64(data []byte) uint32 {
+	h := uint64(fnv64Offset)
+	for _, c := range data {
+		h *= fnv64Prime
+		h ^= uint64(int8(c))
+	}
+	return uint32(h)
+}
+
+// Fnv1a_64 is fnv1a_64, the default hash of twemproxy. It is not the 64 bits FNV-1a but
+// FNV-1a computed in 32 bits with the truncated 64 bits offset basis and prime.
+func Fnv1a_64(data []byte) uint32 {
+	h := uint32(fnv64Offset & 0xffffffff)
+	for _, c := range data {
+		h ^= signed(c)
+		h *= fnv64Prime & 0xffffffff
+	}
+	return h
+}
+
+// Fnv1_32 is fnv1_32, the 32 bits FNV-1 like Fnv32.
+func Fnv1_32(data []byte) uint32 {
+	h := uint32(fnv32Offset)
+	for _, c := range data {
+		h *= fnv32Prime
+		h ^= signed(c)
+	}
+	return h
+}
+
+// Fnv1a_32 is fnv1a_32, the 32 bits FNV-1a.
+func Fnv1a_32(data []byte) uint32 {
+	h := uint32(fnv32Offset)
+	for _, c := range data {
+		h ^= signed(c)
+		h *= fnv32Prime
+	}
+	return h
+}
+
+// Hsieh is hsieh, the SuperFastHash of Paul Hsieh starting from 0 instead of the key length.
+func Hsieh(data []byte) uint32 {
+	var h uint32
+	rem := len(data) & 3
+	for ; len(data) >= 4; data = data[4:] {
+		h += uint32(binary.LittleEndian.Uint16(data))
+		tmp := uint32(binary.LittleEndian.Uint16(data[2:]))<<11 ^ h
+		h = h<<16 ^ tmp
+		h += h >> 11
+	}
+	switch rem {
+	case 3:
+		h += uint32(binary.LittleEndian.Uint16(data))
+		h ^= h << 16
+		h ^= signed(data[2]) << 18
+		h += h >> 11
+	case 2:
+		h += uint32(binary.LittleEndian.Uint16(data))
+		h ^= h << 11
+		h += h >> 17
+	case 1:
+		h += uint32(data[0])
+		h ^= h << 10
+		h += h >> 1
+	}
+	h ^= h << 3
+	h += h >> 5
+	h ^= h << 4
+	h += h >> 17
+	h ^= h << 25
+	h += h >> 6
+	return h
+}
+
+// Murmur2 is murmur, MurmurHash2 seeded like Murmur32.
+func Murmur2(data []byte) uint32 {
+	const m = 0x5bd1e995
+	n := uint32(len(data))
+	h := 0xdeadbeef*n ^ n
+	for ; len(data) >= 4; data = data[4:] {
+		k := binary.LittleEndian.Uint32(data)
+		k *= m
+		k ^= k >> 24
+		k *= m
+		h *= m
+		h ^= k
+	}
+	switch len(data) {
+	case 3:
+		h ^= uint32(data[2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(data[1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(data[0])
+		h *= m
+	}
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+	return h
+}
+
+// Jenkins is jenkins, the lookup3 hashlittle of Bob Jenkins with the initial value 13.
+func Jenkins(data []byte) uint32 {
+	a := 0xdeadbeef + uint32(len(data)) + 13
+	b, c := a, a
+	if len(data) == 0 {
+		return c
+	}
+	for ; len(data) > 12; data = data[12:] {
+		a += binary.LittleEndian.Uint32(data)
+		b += binary.LittleEndian.Uint32(data[4:])
+		c += binary.LittleEndian.Uint32(data[8:])
+		a -= c
+		a ^= bits.RotateLeft32(c, 4)
+		c += b
+		b -= a
+		b ^= bits.RotateLeft32(a, 6)
+		a += c
+		c -= b
+		c ^= bits.RotateLeft32(b, 8)
+		b += a
+		a -= c
+		a ^= bits.RotateLeft32(c, 16)
+		c += b
+		b -= a
+		b ^= bits.RotateLeft32(a, 19)
+		a += c
+		c -= b
+		c ^= bits.RotateLeft32(b, 4)
+		b += a
+	}
+	// the last 1 to 12 bytes, zero padded
+	var tail [12]byte
+	copy(tail[:], data)
+	a += binary.LittleEndian.Uint32(tail[:])
+	b += binary.LittleEndian.Uint32(tail[4:])
+	c += binary.LittleEndian.Uint32(tail[8:])
+	c ^= b
+	c -= bits.RotateLeft32(b, 14)
+	a ^= c
+	a -= bits.RotateLeft32(c, 11)
+	b ^= a
+	b -= bits.RotateLeft32(a, 25)
+	c ^= b
+	c -= bits.RotateLeft32(b, 16)
+	a ^= c
+	a -= bits.RotateLeft32(c, 4)
+	b ^= a
+	b -= bits.RotateLeft32(a, 14)
+	c ^= b
+	c -= bits.RotateLeft32(b, 24)
+	return c
+}
+
+var crc16Table = func() (t [256]uint16) {
+	for i := range t {
+		crc := uint16(i) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+		t[i] = crc
+	}
+	return t
+}()
@@ -0,0 +1,75 @@
+package hashkit
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// wyhashSecret is the default secret of wyhash final version 4.2.
+var wyhashSecret = [4]uint64{0x2d358dccaa6c78a5, 0x8bb84b93962eacc9, 0x4b33a62ed433d4a3, 0x4d5a2da51de1aa47}
+
+// Wyhash is wyhash final version 4.2 of Wang Yi with seed 0, see https://github.com/wangyi-fudan/wyhash.
+func Wyhash(data []byte) uint64 {
+	return wyhash(data, 0)
+}
+
+// WyhashWithSeed returns wyhash with seed.
+func WyhashWithSeed(seed uint64) HashFunc64 {
+	return func(data []byte) uint64 {
+		return wyhash(data, seed)
+	}
+}
+
+func wymum(a, b uint64) (uint64, uint64) {
+	hi, lo := bits.Mul64(a, b)
+	return lo, hi
+}
+
+func wymix(a, b uint64) uint64 {
+	lo, hi := wymum(a, b)
+	return lo ^ hi
+}
+
+func wyr8(b []byte) uint64 {
+	return binary.LittleEndian.Uint64(b)
+}
+
+func wyr4(b []byte) uint64 {
+	return uint64(binary.LittleEndian.Uint32(b))
+}
+
+func wyhash(data []byte, seed uint64) uint64 {
+	s := &wyhashSecret
+	n := len(data)
+	seed ^= wymix(seed^s[0], s[1])
+	var a, b uint64
+	switch {
+	case n == 0:
+	case n < 4:
+		a = uint64(data[0])<<16 | uint64(data[n>>1])<<8 | uint64(data[n-1])
+	case n <= 16:
+		a = wyr4(data)<<32 | wyr4(data[(n>>3)<<2:])
+		b = wyr4(data[n-4:])<<32 | wyr4(data[n-4-((n>>3)<<2):])
+	default:
+		p := data
+		if len(p) >= 48 {
+			see1, see2 := seed, seed
+			for ; len(p) >= 48; p = p[48:] {
+				seed = wymix(wyr8(p)^s[1], wyr8(p[8:])^seed)
+				see1 = wymix(wyr8(p[16:])^s[2], wyr8(p[24:])^see1)
+				see2 = wymix(wyr8(p[32:])^s[3], wyr8(p[40:])^see2)
+			}
+			seed ^= see1 ^ see2
+		}
+		for ; len(p) > 16; p = p[16:] {
+			seed = wymix(wyr8(p)^s[1], wyr8(p[8:])^seed)
+		}
+		// the last 16 bytes may overlap the ones already mixed
+		a = wyr8(data[n-16:])
+		b = wyr8(data[n-8:])
+	}
+	a ^= s[1]
+	b ^= seed
+	a, b = wymum(a, b)
+	return wymix(a^s[0]^uint64(n), b^s[1])
+}
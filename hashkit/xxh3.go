@@ -0,0 +1,293 @@
+package hashkit
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+const (
+	prime32v1 uint64 = 2654435761
+	prime32v2 uint64 = 2246822519
+	prime32v3 uint64 = 3266489917
+
+	xxh3Stripe     = 64
+	xxh3SecretSize = 192
+	// xxh3SecretSizeMin is the smallest secret XXH3 accepts, the last bytes of inputs of
+	// 129 to 240 bytes are mixed with its end.
+	xxh3SecretSizeMin = 136
+	// xxh3Block is the input consumed between two accumulator scrambles.
+	xxh3Block = xxh3Stripe * (xxh3SecretSize - xxh3Stripe) / 8
+)
+
+// xxh3Secret is the default secret of XXH3.
+var xxh3Secret = []byte{
+	0xb8, 0xfe, 0x6c, 0x39, 0x23, 0xa4, 0x4b, 0xbe, 0x7c, 0x01, 0x81, 0x2c, 0xf7, 0x21, 0xad, 0x1c,
+	0xde, 0xd4, 0x6d, 0xe9, 0x83, 0x90, 0x97, 0xdb, 0x72, 0x40, 0xa4, 0xa4, 0xb7, 0xb3, 0x67, 0x1f,
+	0xcb, 0x79, 0xe6, 0x4e, 0xcc, 0xc0, 0xe5, 0x78, 0x82, 0x5a, 0xd0, 0x7d, 0xcc, 0xff, 0x72, 0x21,
+	0xb8, 0x08, 0x46, 0x74, 0xf7, 0x43, 0x24, 0x8e, 0xe0, 0x35, 0x90, 0xe6, 0x81, 0x3a, 0x26, 0x4c,
+	0x3c, 0x28, 0x52, 0xbb, 0x91, 0xc3, 0x00, 0xcb, 0x88, 0xd0, 0x65, 0x8b, 0x1b, 0x53, 0x2e, 0xa3,
+	0x71, 0x64, 0x48, 0x97, 0xa2, 0x0d, 0xf9, 0x4e, 0x38, 0x19, 0xef, 0x46, 0xa9, 0xde, 0xac, 0xd8,
+	0xa8, 0xfa, 0x76, 0x3f, 0xe3, 0x9c, 0x34, 0x3f, 0xf9, 0xdc, 0xbb, 0xc7, 0xc7, 0x0b, 0x4f, 0x1d,
+	0x8a, 0x51, 0xe0, 0x4b, 0xcd, 0xb4, 0x59, 0x31, 0xc8, 0x9f, 0x7e, 0xc9, 0xd9, 0x78, 0x73, 0x64,
+	0xea, 0xc5, 0xac, 0x83, 0x34, 0xd3, 0xeb, 0xc3, 0xc5, 0x81, 0xa0, 0xff, 0xfa, 0x13, 0x63, 0xeb,
+	0x17, 0x0d, 0xdd, 0x51, 0xb7, 0xf0, 0xda, 0x49, 0xd3, 0x16, 0x55, 0x26, 0x29, 0xd4, 0x68, 0x9e,
+	0x2b, 0x16, 0xbe, 0x58, 0x7d, 0x47, 0xa1, 0xfc, 0x8f, 0xf8, 0xb8, 0xd1, 0x7a, 0xd0, 0x31, 0xce,
+	0x45, 0xcb, 0x3a, 0x8f, 0x95, 0x16, 0x04, 0x28, 0xaf, 0xd7, 0xfb, 0xca, 0xbb, 0x4b, 0x40, 0x7e,
+}
+
+// XXH3 is the 64 bits XXH3 of xxHash 0.8 with seed 0.
+func XXH3(data []byte) uint64 {
+	return xxh3(data, 0)
+}
+
+// XXH128 is the 128 bits XXH3 of xxHash 0.8 with seed 0, hi and lo are the high and low
+// halves of the canonical big endian representation.
+func XXH128(data []byte) (hi, lo uint64) {
+	return xxh3x128(data, 0)
+}
+
+// XXH3WithSeed returns the 64 bits XXH3 with seed, inputs longer than 240 bytes are
+// hashed with a secret derived from seed.
+func XXH3WithSeed(seed uint64) HashFunc64 {
+	secret := xxh3SeedSecret(seed)
+	return func(data []byte) uint64 {
+		return xxh3Secreted(data, seed, secret)
+	}
+}
+
+func read64(b []byte, i int) uint64 {
+	return binary.LittleEndian.Uint64(b[i : i+8])
+}
+
+func read32(b []byte, i int) uint32 {
+	return binary.LittleEndian.Uint32(b[i : i+4])
+}
+
+func mulFold64(a, b uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	return hi ^ lo
+}
+
+func xxh3Avalanche(h uint64) uint64 {
+	h ^= h >> 37
+	h *= 0x165667919e3779f9
+	h ^= h >> 32
+	return h
+}
+
+func rrmxmx(h uint64, n int) uint64 {
+	h ^= bits.RotateLeft64(h, 49) ^ bits.RotateLeft64(h, 24)
+	h *= 0x9fb21c651e98df25
+	h ^= (h >> 35) + uint64(n)
+	h *= 0x9fb21c651e98df25
+	h ^= h >> 28
+	return h
+}
+
+func mix16(b []byte, i int, secret []byte, j int, seed uint64) uint64 {
+	return mulFold64(read64(b, i)^(read64(secret, j)+seed), read64(b, i+8)^(read64(secret, j+8)-seed))
+}
+
+// xxh3SeedSecret derives the secret hashing inputs longer than 240 bytes with seed.
+func xxh3SeedSecret(seed uint64) []byte {
+	if seed == 0 {
+		return xxh3Secret
+	}
+	secret := make([]byte, xxh3SecretSize)
+	for i := 0; i < xxh3SecretSize; i += 16 {
+		binary.LittleEndian.PutUint64(secret[i:], read64(xxh3Secret, i)+seed)
+		binary.LittleEndian.PutUint64(secret[i+8:], read64(xxh3Secret, i+8)-seed)
+	}
+	return secret
+}
+
+func xxh3(b []byte, seed uint64) uint64 {
+	if len(b) > 240 {
+		return xxh3Secreted(b, seed, xxh3SeedSecret(seed))
+	}
+	return xxh3Secreted(b, seed, nil)
+}
+
+// xxh3Secreted hashes b with seed, secret is the secret derived from seed and is only
+// used by inputs longer than 240 bytes.
+func xxh3Secreted(b []byte, seed uint64, secret []byte) uint64 {
+	n := len(b)
+	s := xxh3Secret
+	switch {
+	case n == 0:
+		return xxh64Avalanche(seed ^ read64(s, 56) ^ read64(s, 64))
+	case n <= 3:
+		combined := uint32(b[0])<<16 | uint32(b[n>>1])<<24 | uint32(b[n-1]) | uint32(n)<<8
+		bitflip := uint64(read32(s, 0)^read32(s, 4)) + seed
+		return xxh64Avalanche(uint64(combined) ^ bitflip)
+	case n <= 8:
+		seed ^= uint64(bits.ReverseBytes32(uint32(seed))) << 32
+		input := uint64(read32(b, n-4)) + uint64(read32(b, 0))<<32
+		bitflip := (read64(s, 8) ^ read64(s, 16)) - seed
+		return rrmxmx(input^bitflip, n)
+	case n <= 16:
+		lo := read64(b, 0) ^ ((read64(s, 24) ^ read64(s, 32)) + seed)
+		hi := read64(b, n-8) ^ ((read64(s, 40) ^ read64(s, 48)) - seed)
+		return xxh3Avalanche(uint64(n) + bits.ReverseBytes64(lo) + hi + mulFold64(lo, hi))
+	case n <= 128:
+		acc := uint64(n) * prime64v1
+		if n > 32 {
+			if n > 64 {
+				if n > 96 {
+					acc += mix16(b, 48, s, 96, seed)
+					acc += mix16(b, n-64, s, 112, seed)
+				}
+				acc += mix16(b, 32, s, 64, seed)
+				acc += mix16(b, n-48, s, 80, seed)
+			}
+			acc += mix16(b, 16, s, 32, seed)
+			acc += mix16(b, n-32, s, 48, seed)
+		}
+		acc += mix16(b, 0, s, 0, seed)
+		acc += mix16(b, n-16, s, 16, seed)
+		return xxh3Avalanche(acc)
+	case n <= 240:
+		acc := uint64(n) * prime64v1
+		for i := 0; i < 8; i++ {
+			acc += mix16(b, 16*i, s, 16*i, seed)
+		}
+		acc = xxh3Avalanche(acc)
+		for i := 8; i < n/16; i++ {
+			acc += mix16(b, 16*i, s, 16*(i-8)+3, seed)
+		}
+		acc += mix16(b, n-16, s, xxh3SecretSizeMin-17, seed)
+		return xxh3Avalanche(acc)
+	}
+	acc := xxh3Long(b, secret)
+	return xxh3Merge(&acc, secret, 11, uint64(n)*prime64v1)
+}
+
+func xxh3x128(b []byte, seed uint64) (hi, lo uint64) {
+	n := len(b)
+	s := xxh3Secret
+	switch {
+	case n == 0:
+		return xxh64Avalanche(seed ^ read64(s, 80) ^ read64(s, 88)), xxh64Avalanche(seed ^ read64(s, 64) ^ read64(s, 72))
+	case n <= 3:
+		combinedl := uint32(b[0])<<16 | uint32(b[n>>1])<<24 | uint32(b[n-1]) | uint32(n)<<8
+		combinedh := bits.RotateLeft32(bits.ReverseBytes32(combinedl), 13)
+		bitflipl := uint64(read32(s, 0)^read32(s, 4)) + seed
+		bitfliph := uint64(read32(s, 8)^read32(s, 12)) - seed
+		return xxh64Avalanche(uint64(combinedh) ^ bitfliph), xxh64Avalanche(uint64(combinedl) ^ bitflipl)
+	case n <= 8:
+		seed ^= uint64(bits.ReverseBytes32(uint32(seed))) << 32
+		input := uint64(read32(b, 0)) + uint64(read32(b, n-4))<<32
+		bitflip := (read64(s, 16) ^ read64(s, 24)) + seed
+		hi, lo = bits.Mul64(input^bitflip, prime64v1+uint64(n)<<2)
+		hi += lo << 1
+		lo ^= hi >> 3
+		lo ^= lo >> 35
+		lo *= 0x9fb21c651e98df25
+		lo ^= lo >> 28
+		return xxh3Avalanche(hi), lo
+	case n <= 16:
+		bitflipl := (read64(s, 32) ^ read64(s, 40)) - seed
+		bitfliph := (read64(s, 48) ^ read64(s, 56)) + seed
+		inputLo := read64(b, 0)
+		inputHi := read64(b, n-8)
+		mhi, mlo := bits.Mul64(inputLo^inputHi^bitflipl, prime64v1)
+		mlo += uint64(n-1) << 54
+		inputHi ^= bitfliph
+		mhi += inputHi + uint64(uint32(inputHi))*(prime32v2-1)
+		mlo ^= bits.ReverseBytes64(mhi)
+		hi, lo = bits.Mul64(mlo, prime64v2)
+		hi += mhi * prime64v2
+		return xxh3Avalanche(hi), xxh3Avalanche(lo)
+	case n <= 128:
+		acc := [2]uint64{uint64(n) * prime64v1, 0}
+		if n > 32 {
+			if n > 64 {
+				if n > 96 {
+					mix32(&acc, b, 48, n-64, s, 96, seed)
+				}
+				mix32(&acc, b, 32, n-48, s, 64, seed)
+			}
+			mix32(&acc, b, 16, n-32, s, 32, seed)
+		}
+		mix32(&acc, b, 0, n-16, s, 0, seed)
+		return xxh3Finish128(acc, n, seed)
+	case n <= 240:
+		acc := [2]uint64{uint64(n) * prime64v1, 0}
+		for i := 0; i < 4; i++ {
+			mix32(&acc, b, 32*i, 32*i+16, s, 32*i, seed)
+		}
+		acc[0], acc[1] = xxh3Avalanche(acc[0]), xxh3Avalanche(acc[1])
+		for i := 4; i < n/32; i++ {
+			mix32(&acc, b, 32*i, 32*i+16, s, 32*(i-4)+3, seed)
+		}
+		mix32(&acc, b, n-16, n-32, s, xxh3SecretSizeMin-17-16, -seed)
+		return xxh3Finish128(acc, n, seed)
+	}
+	s = xxh3SeedSecret(seed)
+	acc := xxh3Long(b, s)
+	lo = xxh3Merge(&acc, s, 11, uint64(n)*prime64v1)
+	hi = xxh3Merge(&acc, s, xxh3SecretSize-64-11, ^(uint64(n) * prime64v2))
+	return hi, lo
+}
+
+func mix32(acc *[2]uint64, b []byte, i, j int, secret []byte, k int, seed uint64) {
+	acc[0] += mix16(b, i, secret, k, seed)
+	acc[0] ^= read64(b, j) + read64(b, j+8)
+	acc[1] += mix16(b, j, secret, k+16, seed)
+	acc[1] ^= read64(b, i) + read64(b, i+8)
+}
+
+func xxh3Finish128(acc [2]uint64, n int, seed uint64) (hi, lo uint64) {
+	lo = acc[0] + acc[1]
+	hi = acc[0]*prime64v1 + acc[1]*prime64v4 + (uint64(n)-seed)*prime64v2
+	return -xxh3Avalanche(hi), xxh3Avalanche(lo)
+}
+
+// xxh3Long runs the accumulators over inputs longer than 240 bytes.
+func xxh3Long(b []byte, secret []byte) [8]uint64 {
+	acc := [8]uint64{prime32v3, prime64v1, prime64v2, prime64v3, prime64v4, prime32v2, prime64v5, prime32v1}
+	n := len(b)
+	stripes := (xxh3SecretSize - xxh3Stripe) / 8
+	blocks := (n - 1) / xxh3Block
+	for i := 0; i < blocks; i++ {
+		xxh3Accumulate(&acc, b[i*xxh3Block:], secret, stripes)
+		xxh3Scramble(&acc, secret[xxh3SecretSize-xxh3Stripe:])
+	}
+	stripes = (n - 1 - blocks*xxh3Block) / xxh3Stripe
+	xxh3Accumulate(&acc, b[blocks*xxh3Block:], secret, stripes)
+	xxh3Accumulate512(&acc, b[n-xxh3Stripe:], secret[xxh3SecretSize-xxh3Stripe-7:])
+	return acc
+}
+
+func xxh3Accumulate(acc *[8]uint64, b []byte, secret []byte, stripes int) {
+	for i := 0; i < stripes; i++ {
+		xxh3Accumulate512(acc, b[i*xxh3Stripe:], secret[i*8:])
+	}
+}
+
+func xxh3Accumulate512(acc *[8]uint64, b []byte, secret []byte) {
+	b, secret = b[:xxh3Stripe], secret[:xxh3Stripe] // eliminates the bounds checks below
+	for i := 0; i < 8; i++ {
+		v := binary.LittleEndian.Uint64(b[8*i:])
+		k := v ^ binary.LittleEndian.Uint64(secret[8*i:])
+		acc[i^1] += v
+		acc[i] += uint64(uint32(k)) * (k >> 32)
+	}
+}
+
+func xxh3Scramble(acc *[8]uint64, secret []byte) {
+	for i := range acc {
+		a := acc[i]
+		a ^= a >> 47
+		a ^= read64(secret, 8*i)
+		acc[i] = a * prime32v1
+	}
+}
+
+func xxh3Merge(acc *[8]uint64, secret []byte, i int, start uint64) uint64 {
+	h := start
+	for j := 0; j < 4; j++ {
+		h += mulFold64(acc[2*j]^read64(secret, i+16*j), acc[2*j+1]^read64(secret, i+16*j+8))
+	}
+	return xxh3Avalanche(h)
+}
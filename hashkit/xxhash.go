@@ -0,0 +1,87 @@
+package hashkit
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+const (
+	prime64v1 uint64 = 11400714785074694791
+	prime64v2 uint64 = 14029467366897019727
+	prime64v3 uint64 = 1609587929392839161
+	prime64v4 uint64 = 9650029242287828579
+	prime64v5 uint64 = 2870177450012600261
+)
+
+// XXHash64 is the 64 bits xxHash of Yann Collet with seed 0, see
+// https://github.com/Cyan4973/xxHash/blob/dev/doc/xxhash_spec.md.
+func XXHash64(data []byte) uint64 {
+	return xxh64(data, 0)
+}
+
+// XXHash64WithSeed returns the 64 bits xxHash with seed.
+func XXHash64WithSeed(seed uint64) HashFunc64 {
+	return func(data []byte) uint64 {
+		return xxh64(data, seed)
+	}
+}
+
+func xxh64(b []byte, seed uint64) uint64 {
+	n := len(b)
+	var h uint64
+	if n >= 32 {
+		v1 := seed + prime64v1 + prime64v2
+		v2 := seed + prime64v2
+		v3 := seed
+		v4 := seed - prime64v1
+		for ; len(b) >= 32; b = b[32:] {
+			v1 = xxh64Round(v1, binary.LittleEndian.Uint64(b[0:8]))
+			v2 = xxh64Round(v2, binary.LittleEndian.Uint64(b[8:16]))
+			v3 = xxh64Round(v3, binary.LittleEndian.Uint64(b[16:24]))
+			v4 = xxh64Round(v4, binary.LittleEndian.Uint64(b[24:32]))
+		}
+		h = bits.RotateLeft64(v1, 1) + bits.RotateLeft64(v2, 7) + bits.RotateLeft64(v3, 12) + bits.RotateLeft64(v4, 18)
+		h = xxh64MergeRound(h, v1)
+		h = xxh64MergeRound(h, v2)
+		h = xxh64MergeRound(h, v3)
+		h = xxh64MergeRound(h, v4)
+	} else {
+		h = seed + prime64v5
+	}
+	h += uint64(n)
+
+	for ; len(b) >= 8; b = b[8:] {
+		h ^= xxh64Round(0, binary.LittleEndian.Uint64(b))
+		h = bits.RotateLeft64(h, 27)*prime64v1 + prime64v4
+	}
+	if len(b) >= 4 {
+		h ^= uint64(binary.LittleEndian.Uint32(b)) * prime64v1
+		h = bits.RotateLeft64(h, 23)*prime64v2 + prime64v3
+		b = b[4:]
+	}
+	for _, c := range b {
+		h ^= uint64(c) * prime64v5
+		h = bits.RotateLeft64(h, 11) * prime64v1
+	}
+	return xxh64Avalanche(h)
+}
+
+func xxh64Round(acc, input uint64) uint64 {
+	acc += input * prime64v2
+	acc = bits.RotateLeft64(acc, 31)
+	return acc * prime64v1
+}
+
+func xxh64MergeRound(acc, v uint64) uint64 {
+	acc ^= xxh64Round(0, v)
+	return acc*prime64v1 + prime64v4
+}
+
+func xxh64Avalanche(h uint64) uint64 {
+	h ^= h >> 33
+	h *= prime64v2
+	h ^= h >> 29
+	h *= prime64v3
+	h ^= h >> 32
+	return h
+}
@@ -0,0 +1,332 @@
+package hyperloglog
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/bits"
+	"sort"
+
+	"github.com/zjbztianya/go-misc/hashkit"
+)
+
+const (
+	MinPrecision = 4
+	MaxPrecision = 18
+
+	// sparsePrecision is the index precision of the sparse representation, so small
+	// cardinalities are estimated with 2^25 registers.
+	sparsePrecision = 25
+
+	encodingVersion = 1
+	sparseEncoding  = 0
+	denseEncoding   = 1
+)
+
+var hashString = hashkit.String64(hashkit.Murmur64)
+
+var errPrecision = errors.New("hyperloglog precisions differ")
+
+// Sketch is a HyperLogLog++ cardinality estimator, see Heule et al., "HyperLogLog in
+// Practice: Algorithmic Engineering of a State of The Art Cardinality Estimation
+// Algorithm". Small sets are kept in a sparse list of hashes, large ones in 2^precision
+// registers estimated with the improved estimator of Ertl, "New cardinality estimation
+// algorithms for HyperLogLog sketches", which needs no empirical bias correction.
+// The standard error is 1.04/sqrt(2^precision). A Sketch is not safe for concurrent use.
+type Sketch struct {
+	p         uint8
+	registers []uint8  // dense representation, nil while sparse
+	sparse    []uint32 // sorted, one entry per sparse index
+	tmp       []uint32 // unsorted sparse entries not merged into sparse yet
+}
+
+// New creates a sketch of 2^precision registers, it panics if precision is not
+// in [MinPrecision, MaxPrecision].
+func New(precision uint8) *Sketch {
+	if precision < MinPrecision || precision > MaxPrecision {
+		panic("hyperloglog precision must be in [4, 18]")
+	}
+	return &Sketch{p: precision}
+}
+
+func (s *Sketch) Precision() uint8 {
+	return s.p
+}
+
+func (s *Sketch) AddString(key string) {
+	s.add(hashString(key))
+}
+
+func (s *Sketch) Add(data []byte) {
+	s.add(hashkit.Murmur64(data))
+}
+
+func (s *Sketch) add(x uint64) {
+	if s.registers != nil {
+		s.addDense(x)
+		return
+	}
+	s.tmp = append(s.tmp, encodeSparse(x))
+	if len(s.tmp) >= s.maxSparse()/4 {
+		s.flush()
+	}
+}
+
+func (s *Sketch) addDense(x uint64) {
+	idx := x >> (64 - s.p)
+	rho := uint8(bits.LeadingZeros64(x<<s.p|1<<(s.p-1))) + 1
+	if rho > s.registers[idx] {
+		s.registers[idx] = rho
+	}
+}
+
+// maxSparse is the number of sparse entries using as much memory as the registers.
+func (s *Sketch) maxSparse() int {
+	return (1 << s.p) / 4
+}
+
+// encodeSparse packs the sparse index of x and the rank of its remaining bits.
+func encodeSparse(x uint64) uint32 {
+	idx := uint32(x >> (64 - sparsePrecision))
+	rho := uint32(bits.LeadingZeros64(x<<sparsePrecision|1<<(sparsePrecision-1))) + 1
+	return idx<<6 | rho
+}
+
+// decodeSparse returns the dense register index and rank of a sparse entry.
+func decodeSparse(k uint32, p uint8) (uint32, uint8) {
+	idx := k >> 6
+	rho := uint8(k & 0x3f)
+	extra := sparsePrecision - uint32(p)
+	if low := idx & (1<<extra - 1); low != 0 {
+		// the first one bit is in the index bits the dense representation drops
+		rho = uint8(extra) - uint8(bits.Len32(low)) + 1
+	} else {
+		rho += uint8(extra)
+	}
+	return idx >> extra, rho
+}
+
+// flush merges tmp into sparse and switches to the dense representation once the
+// sparse one would take more memory.
+func (s *Sketch) flush() {
+	if len(s.tmp) == 0 {
+		return
+	}
+	sort.Slice(s.tmp, func(i, j int) bool { return s.tmp[i] < s.tmp[j] })
+	s.sparse = mergeSparse(s.sparse, s.tmp)
+	s.tmp = s.tmp[:0]
+	if len(s.sparse) > s.maxSparse() {
+		s.toDense()
+	}
+}
+
+// mergeSparse merges two sorted lists, keeping the highest rank of every index.
+func mergeSparse(a, b []uint32) []uint32 {
+	merged := make([]uint32, 0, len(a)+len(b))
+	add := func(k uint32) {
+		if n := len(merged); n > 0 && merged[n-1]>>6 == k>>6 {
+			// same index, entries are sorted so k has the higher rank
+			merged[n-1] = k
+			return
+		}
+		merged = append(merged, k)
+	}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		if j == len(b) || (i < len(a) && a[i] < b[j]) {
+			add(a[i])
+			i++
+		} else {
+			add(b[j])
+			j++
+		}
+	}
+	return merged
+}
+
+func (s *Sketch) toDense() {
+	s.registers = make([]uint8, 1<<s.p)
+	for _, k := range s.sparse {
+		s.addSparseEntry(k)
+	}
+	for _, k := range s.tmp {
+		s.addSparseEntry(k)
+	}
+	s.sparse, s.tmp = nil, nil
+}
+
+func (s *Sketch) addSparseEntry(k uint32) {
+	idx, rho := decodeSparse(k, s.p)
+	if rho > s.registers[idx] {
+		s.registers[idx] = rho
+	}
+}
+
+// Count estimates the number of distinct keys added.
+func (s *Sketch) Count() uint64 {
+	if s.registers == nil {
+		s.flush()
+	}
+	if s.registers == nil {
+		// linear counting over the sparse registers
+		m := float64(uint64(1) << sparsePrecision)
+		return uint64(math.Round(m * math.Log(m/(m-float64(len(s.sparse))))))
+	}
+
+	q := 64 - int(s.p)
+	m := float64(len(s.registers))
+	counts := make([]float64, q+2)
+	for _, r := range s.registers {
+		counts[r]++
+	}
+	z := m * tau(1-counts[q+1]/m)
+	for k := q; k >= 1; k-- {
+		z = 0.5 * (z + counts[k])
+	}
+	z += m * sigma(counts[0]/m)
+	return uint64(math.Round(m * m / (2 * math.Ln2 * z)))
+}
+
+func sigma(x float64) float64 {
+	if x == 1 {
+		return math.Inf(1)
+	}
+	y, z := 1.0, x
+	for {
+		x *= x
+		prev := z
+		z += x * y
+		y += y
+		if z == prev {
+			return z
+		}
+	}
+}
+
+func tau(x float64) float64 {
+	if x == 0 || x == 1 {
+		return 0
+	}
+	y, z := 1.0, 1-x
+	for {
+		x = math.Sqrt(x)
+		prev := z
+		y *= 0.5
+		z -= (1 - x) * (1 - x) * y
+		if z == prev {
+			return z / 3
+		}
+	}
+}
+
+// Merge adds the keys of o to s, both must have the same precision.
+func (s *Sketch) Merge(o *Sketch) error {
+	if s.p != o.p {
+		return errPrecision
+	}
+	if s.registers == nil {
+		s.flush()
+	}
+	if o.registers == nil && s.registers == nil {
+		s.tmp = append(s.tmp, o.sparse...)
+		s.tmp = append(s.tmp, o.tmp...)
+		s.flush()
+		return nil
+	}
+
+	if s.registers == nil {
+		s.toDense()
+	}
+	if o.registers == nil {
+		for _, k := range o.sparse {
+			s.addSparseEntry(k)
+		}
+		for _, k := range o.tmp {
+			s.addSparseEntry(k)
+		}
+		return nil
+	}
+	for i, r := range o.registers {
+		if r > s.registers[i] {
+			s.registers[i] = r
+		}
+	}
+	return nil
+}
+
+// MarshalBinary encodes the sketch as a version, the precision and the representation:
+// the delta encoded sparse entries or a byte per register.
+func (s *Sketch) MarshalBinary() ([]byte, error) {
+	if s.registers == nil {
+		s.flush()
+	}
+	if s.registers != nil {
+		buf := make([]byte, 3, 3+len(s.registers))
+		buf[0], buf[1], buf[2] = encodingVersion, s.p, denseEncoding
+		return append(buf, s.registers...), nil
+	}
+
+	buf := make([]byte, 3, 3+binary.MaxVarintLen32*(len(s.sparse)+1))
+	buf[0], buf[1], buf[2] = encodingVersion, s.p, sparseEncoding
+	var tmp [binary.MaxVarintLen64]byte
+	buf = append(buf, tmp[:binary.PutUvarint(tmp[:], uint64(len(s.sparse)))]...)
+	var prev uint32
+	for _, k := range s.sparse {
+		buf = append(buf, tmp[:binary.PutUvarint(tmp[:], uint64(k-prev))]...)
+		prev = k
+	}
+	return buf, nil
+}
+
+func (s *Sketch) UnmarshalBinary(data []byte) error {
+	if len(data) < 3 || data[0] != encodingVersion {
+		return errors.New("hyperloglog: unsupported encoding")
+	}
+	p, encoding := data[1], data[2]
+	if p < MinPrecision || p > MaxPrecision {
+		return errors.New("hyperloglog: invalid precision")
+	}
+	data = data[3:]
+	switch encoding {
+	case denseEncoding:
+		if len(data) != 1<<p {
+			return errors.New("hyperloglog: invalid register count")
+		}
+		for _, r := range data {
+			if int(r) > 65-int(p) {
+				return errors.New("hyperloglog: invalid register")
+			}
+		}
+		*s = Sketch{p: p, registers: append([]uint8(nil), data...)}
+	case sparseEncoding:
+		n, read := binary.Uvarint(data)
+		if read <= 0 || n > uint64(len(data)) {
+			return errors.New("hyperloglog: invalid sparse length")
+		}
+		data = data[read:]
+		sparse := make([]uint32, n)
+		var prev uint64
+		for i := range sparse {
+			delta, read := binary.Uvarint(data)
+			if read <= 0 || (i > 0 && delta == 0) || prev+delta > math.MaxUint32 {
+				return errors.New("hyperloglog: invalid sparse entry")
+			}
+			data = data[read:]
+			prev += delta
+			if rho := prev & 0x3f; rho == 0 || rho > 64-sparsePrecision+1 || prev>>6 >= 1<<sparsePrecision {
+				return errors.New("hyperloglog: invalid sparse entry")
+			}
+			sparse[i] = uint32(prev)
+		}
+		if len(data) != 0 {
+			return errors.New("hyperloglog: trailing data")
+		}
+		*s = Sketch{p: p, sparse: sparse}
+		if len(sparse) > s.maxSparse() {
+			s.toDense()
+		}
+	default:
+		return errors.New("hyperloglog: unsupported encoding")
+	}
+	return nil
+}
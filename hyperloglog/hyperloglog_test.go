@@ -0,0 +1,155 @@
+package hyperloglog
+
+import (
+	"encoding/binary"
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	s := New(14)
+	assert.Equal(t, uint8(14), s.Precision())
+	assert.Equal(t, uint64(0), s.Count())
+	assert.Panics(t, func() {
+		New(3)
+	})
+	assert.Panics(t, func() {
+		New(19)
+	})
+}
+
+func TestSparseEncoding(t *testing.T) {
+	// the first one bit after the dense index is in the sparse index
+	x := uint64(0x3)<<(64-sparsePrecision) | 1<<20
+	k := encodeSparse(x)
+	assert.Equal(t, uint32(3), k>>6)
+	assert.Equal(t, uint32(19), k&0x3f)
+	idx, rho := decodeSparse(k, 14)
+	assert.Equal(t, uint32(0), idx)
+	assert.Equal(t, uint8(10), rho)
+
+	// the first one bit is after the sparse index
+	x = uint64(1)<<(64-14) | 1<<20
+	idx, rho = decodeSparse(encodeSparse(x), 14)
+	assert.Equal(t, uint32(1), idx)
+	assert.Equal(t, uint8(30), rho)
+
+	// both representations agree
+	dense := New(14)
+	dense.registers = make([]uint8, 1<<14)
+	dense.addDense(x)
+	assert.Equal(t, rho, dense.registers[1])
+}
+
+func TestSketchDuplicates(t *testing.T) {
+	s := New(14)
+	for i := 0; i < 100; i++ {
+		s.AddString("user")
+	}
+	assert.Equal(t, uint64(1), s.Count())
+	assert.Nil(t, s.registers)
+}
+
+func TestSketchAccuracy(t *testing.T) {
+	for _, p := range []uint8{10, 14} {
+		stdErr := 1.04 / math.Sqrt(float64(uint64(1)<<p))
+		s := New(p)
+		n := 0
+		for _, exact := range []int{10, 100, 1000, 5000, 10000, 50000, 100000, 500000} {
+			for ; n < exact; n++ {
+				s.AddString("user-" + strconv.Itoa(n))
+				if n%3 == 0 {
+					s.AddString("user-" + strconv.Itoa(n/2))
+				}
+			}
+			estimate := float64(s.Count())
+			// small sparse cardinalities are nearly exact
+			maxErr := 4 * stdErr
+			if s.registers == nil {
+				maxErr = 0.01
+			}
+			assert.InEpsilon(t, float64(exact), estimate, maxErr, "precision %d, %d keys", p, exact)
+		}
+		assert.NotNil(t, s.registers)
+	}
+}
+
+func TestSketchMerge(t *testing.T) {
+	for _, sizes := range [][2]int{{100, 200}, {100, 50000}, {50000, 100}, {50000, 60000}} {
+		a, b, exact := New(12), New(12), New(12)
+		for i := 0; i < sizes[0]; i++ {
+			a.AddString(strconv.Itoa(i))
+			exact.AddString(strconv.Itoa(i))
+		}
+		// half of the keys of b are in a
+		for i := sizes[0] / 2; i < sizes[0]/2+sizes[1]; i++ {
+			b.AddString(strconv.Itoa(i))
+			exact.AddString(strconv.Itoa(i))
+		}
+		assert.Nil(t, a.Merge(b))
+		assert.Equal(t, exact.Count(), a.Count(), "sizes %v", sizes)
+	}
+	assert.Equal(t, errPrecision, New(12).Merge(New(13)))
+}
+
+func TestSketchMarshalBinary(t *testing.T) {
+	for _, n := range []int{0, 10, 1000, 100000} {
+		s := New(12)
+		for i := 0; i < n; i++ {
+			s.AddString(strconv.Itoa(i))
+		}
+		data, err := s.MarshalBinary()
+		assert.Nil(t, err)
+		var decoded Sketch
+		assert.Nil(t, decoded.UnmarshalBinary(data))
+		assert.Equal(t, s.Count(), decoded.Count())
+		assert.Equal(t, uint8(12), decoded.Precision())
+		if n == 1000 {
+			// sparse entries are delta encoded
+			assert.Less(t, len(data), 4*1000)
+		}
+	}
+
+	var s Sketch
+	assert.NotNil(t, s.UnmarshalBinary(nil))
+	assert.NotNil(t, s.UnmarshalBinary([]byte{encodingVersion, 30, denseEncoding}))
+	assert.NotNil(t, s.UnmarshalBinary([]byte{encodingVersion, 4, denseEncoding, 1}))
+	assert.NotNil(t, s.UnmarshalBinary([]byte{encodingVersion, 4, sparseEncoding, 2, 1}))
+	assert.NotNil(t, s.UnmarshalBinary([]byte{encodingVersion, 4, 7}))
+}
+
+func TestUnmarshalSparseEntries(t *testing.T) {
+	entry := func(idx, rho uint64) []byte {
+		var buf [binary.MaxVarintLen64]byte
+		data := []byte{encodingVersion, 4, sparseEncoding, 1}
+		return append(data, buf[:binary.PutUvarint(buf[:], idx<<6|rho)]...)
+	}
+	var s Sketch
+	assert.Nil(t, s.UnmarshalBinary(entry(1<<sparsePrecision-1, 64-sparsePrecision+1)))
+	dense := New(4)
+	for i := 0; i < 100; i++ {
+		dense.AddString(strconv.Itoa(i))
+	}
+	assert.Nil(t, dense.Merge(&s))
+	assert.NotZero(t, dense.Count())
+
+	assert.NotNil(t, s.UnmarshalBinary([]byte{encodingVersion, 4, sparseEncoding, 1, 63}))
+	assert.NotNil(t, s.UnmarshalBinary(entry(1, 0)))
+	assert.NotNil(t, s.UnmarshalBinary(entry(1, 64-sparsePrecision+2)))
+	assert.NotNil(t, s.UnmarshalBinary(entry(1<<sparsePrecision, 1)))
+}
+
+func BenchmarkSketchAdd(b *testing.B) {
+	keys := make([][]byte, 1024)
+	for i := range keys {
+		keys[i] = []byte(strconv.Itoa(i))
+	}
+	s := New(14)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Add(keys[i%len(keys)])
+	}
+}
@@ -0,0 +1,122 @@
+package metrics
+
+import (
+	"time"
+)
+
+// Tier is one resolution of an Archive: Size buckets of Interval each.
+type Tier struct {
+	Interval time.Duration
+	Size     int
+}
+
+// Point is one bucket returned by an Archive range query.
+type Point struct {
+	Start    time.Time
+	Interval time.Duration
+	Bucket   Bucket
+}
+
+// Archive is a multi-resolution round-robin series in the spirit of RRDtool.
+// Every sample is consolidated into all tiers, e.g. 1s buckets for the last minute,
+// 1m buckets for the last day and 1h buckets for a month. Bucket boundaries are
+// aligned to the Unix epoch so coarse buckets start exactly where fine ones do.
+type Archive struct {
+	tiers []*SlidingWindow
+}
+
+// NewArchive creates an archive, tiers must be ordered from finest to coarsest
+// and each interval must be a multiple of the previous one.
+func NewArchive(tiers ...Tier) *Archive {
+	if len(tiers) == 0 {
+		panic("archive needs at least one tier")
+	}
+	a := &Archive{tiers: make([]*SlidingWindow, len(tiers))}
+	for i, t := range tiers {
+		if t.Interval <= 0 {
+			panic("archive tier interval must greater than 0")
+		}
+		if i > 0 && (t.Interval <= tiers[i-1].Interval || t.Interval%tiers[i-1].Interval != 0) {
+			panic("archive tier interval must be a multiple of the previous tier")
+		}
+		a.tiers[i] = NewSlidingWindow(t.Size, t.Interval, WithEpochAlignment())
+	}
+	return a
+}
+
+func (a *Archive) Inc() {
+	a.Add(1)
+}
+
+func (a *Archive) Add(v float64) {
+	for _, w := range a.tiers {
+		w.Add(v)
+	}
+}
+
+// Snapshot returns the snapshot of the i-th tier.
+func (a *Archive) Snapshot(i int) *Snapshot {
+	return a.tiers[i].Snapshot()
+}
+
+// Query returns the buckets overlapping [from, to), oldest first. Recent data comes
+// from the finest tier that still holds it and older data from coarser tiers, the
+// buckets of different tiers never overlap.
+func (a *Archive) Query(from, to time.Time) []Point {
+	snaps := make([]*Snapshot, len(a.tiers))
+	for i, w := range a.tiers {
+		snaps[i] = w.Snapshot()
+	}
+
+	var points []Point
+	upper := snaps[0].End()
+	for i, s := range snaps {
+		if start := alignTime(upper, s.Interval()); i > 0 && !start.Equal(upper) {
+			// the finer tiers do not reach back to the start of the bucket of this tier
+			// holding upper, that bucket replaces what they returned after its start
+			n := 0
+			for n < len(points) && !points[n].Start.Before(start) {
+				n++
+			}
+			points = points[n:]
+			upper = start.Add(s.Interval())
+		}
+		// hand the part of this tier that is not a whole bucket of the next tier over to it
+		lower := s.Start()
+		if i+1 < len(snaps) {
+			lower = ceilTime(lower, snaps[i+1].Interval())
+			if lower.After(s.End()) {
+				lower = s.End()
+			}
+		}
+		// collect newest first, reversed at the end
+		for j := s.Len() - 1; j >= 0; j-- {
+			start := s.BucketStart(j)
+			end := start.Add(s.Interval())
+			if start.Before(lower) || !end.After(from) {
+				break
+			}
+			if end.After(upper) || !start.Before(to) {
+				continue
+			}
+			points = append(points, Point{Start: start, Interval: s.Interval(), Bucket: s.Bucket(j)})
+		}
+		if lower.Before(upper) {
+			upper = lower
+		}
+	}
+
+	for i, j := 0, len(points)-1; i < j; i, j = i+1, j-1 {
+		points[i], points[j] = points[j], points[i]
+	}
+	return points
+}
+
+// ceilTime rounds t up to a multiple of interval since the Unix epoch.
+func ceilTime(t time.Time, interval time.Duration) time.Time {
+	aligned := alignTime(t, interval)
+	if aligned.Equal(t) {
+		return t
+	}
+	return aligned.Add(interval)
+}
@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewArchive(t *testing.T) {
+	assert.NotNil(t, NewArchive(Tier{Interval: time.Second, Size: 60}, Tier{Interval: time.Minute, Size: 60}))
+	assert.Panics(t, func() {
+		NewArchive()
+	})
+	assert.Panics(t, func() {
+		NewArchive(Tier{Interval: time.Minute, Size: 60}, Tier{Interval: time.Second, Size: 60})
+	})
+	assert.Panics(t, func() {
+		NewArchive(Tier{Interval: 2 * time.Second, Size: 60}, Tier{Interval: 3 * time.Second, Size: 60})
+	})
+}
+
+func TestArchiveQuery(t *testing.T) {
+	fine, coarse := 20*time.Millisecond, 100*time.Millisecond
+	a := NewArchive(Tier{Interval: fine, Size: 5}, Tier{Interval: coarse, Size: 10})
+	for i := 0; i < 30; i++ {
+		a.Inc()
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	points := a.Query(time.Time{}, time.Now().Add(time.Hour))
+	var count int64
+	for i, p := range points {
+		count += p.Bucket.Count
+		if i > 0 {
+			prev := points[i-1]
+			assert.False(t, p.Start.Before(prev.Start.Add(prev.Interval)))
+		}
+	}
+	assert.Equal(t, int64(30), count)
+	last := points[len(points)-1]
+	assert.Equal(t, fine, last.Interval)
+	assert.Equal(t, coarse, points[0].Interval)
+
+	now := time.Now()
+	for _, p := range a.Query(now.Add(-fine), now) {
+		assert.True(t, p.Start.Before(now))
+		assert.True(t, p.Start.Add(p.Interval).After(now.Add(-fine)))
+	}
+	assert.Empty(t, a.Query(now.Add(-time.Hour), now.Add(-30*time.Minute)))
+}
+
+func TestArchiveQueryShortTiers(t *testing.T) {
+	// the finer tiers hold less than a bucket of the coarsest one
+	for _, tiers := range [][]Tier{
+		{{Interval: time.Second, Size: 10}, {Interval: time.Hour, Size: 24}},
+		{{Interval: time.Second, Size: 10}, {Interval: time.Minute, Size: 1}, {Interval: time.Hour, Size: 24}},
+	} {
+		a := NewArchive(tiers...)
+		a.Add(5)
+		now := time.Now()
+		points := a.Query(now.Add(-2*time.Hour), now.Add(time.Second))
+		var sum float64
+		for i, p := range points {
+			sum += p.Bucket.Sum
+			if i > 0 {
+				prev := points[i-1]
+				assert.False(t, p.Start.Before(prev.Start.Add(prev.Interval)))
+			}
+		}
+		assert.Equal(t, a.Snapshot(len(tiers)-1).Total().Sum, sum)
+		assert.Equal(t, float64(5), sum)
+	}
+}
@@ -0,0 +1,28 @@
+package metrics
+
+var _ ValueCounter = (*counter)(nil)
+
+// counter is a gauge that only goes up.
+type counter struct {
+	g gauge
+}
+
+func NewCounter() ValueCounter {
+	return &counter{}
+}
+
+func (c *counter) Inc() {
+	c.g.Add(1)
+}
+
+// Add panics if delta is negative.
+func (c *counter) Add(delta float64) {
+	if delta < 0 {
+		panic("counter cannot decrease in value")
+	}
+	c.g.Add(delta)
+}
+
+func (c *counter) Value() float64 {
+	return c.g.Value()
+}
@@ -0,0 +1,31 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCounterAdd(t *testing.T) {
+	c := NewCounter()
+	c.Inc()
+	c.Add(2.5)
+	assert.Equal(t, 3.5, c.Value())
+	assert.Panics(t, func() {
+		c.Add(-1)
+	})
+}
+
+// writeOnlyCounter is a Counter implemented before ValueCounter existed.
+type writeOnlyCounter struct{}
+
+func (writeOnlyCounter) Inc()              {}
+func (writeOnlyCounter) Add(delta float64) {}
+
+func TestWriteOnlyCounter(t *testing.T) {
+	var c Counter = writeOnlyCounter{}
+	c.Inc()
+	r := NewRegistry()
+	assert.Nil(t, r.Register("write_only", c))
+	assert.Empty(t, r.Dump())
+}
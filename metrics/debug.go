@@ -0,0 +1,200 @@
+package metrics
+
+import (
+	"encoding/json"
+	"expvar"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// jsonFloat encodes NaN and infinities, which encoding/json rejects, as strings.
+type jsonFloat float64
+
+func (f jsonFloat) MarshalJSON() ([]byte, error) {
+	v := float64(f)
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return []byte(`"` + strconv.FormatFloat(v, 'g', -1, 64) + `"`), nil
+	}
+	return json.Marshal(v)
+}
+
+type debugValue struct {
+	Type    string               `json:"type"`
+	Labels  Labels               `json:"labels,omitempty"`
+	Value   *jsonFloat           `json:"value,omitempty"`
+	Stats   map[string]jsonFloat `json:"stats,omitempty"`
+	Buckets interface{}          `json:"buckets,omitempty"`
+	Window  *debugWindow         `json:"window,omitempty"`
+}
+
+// debugBucket is a Bucket whose NaN and infinite samples survive the encoding.
+type debugBucket struct {
+	Sum   jsonFloat `json:"sum,omitempty"`
+	Count int64     `json:"count,omitempty"`
+	Min   jsonFloat `json:"min,omitempty"`
+	Max   jsonFloat `json:"max,omitempty"`
+	Last  jsonFloat `json:"last,omitempty"`
+	SumSq jsonFloat `json:"sumsq,omitempty"`
+}
+
+func newDebugBucket(b *Bucket) debugBucket {
+	return debugBucket{
+		Sum:   jsonFloat(b.Sum),
+		Count: b.Count,
+		Min:   jsonFloat(b.Min),
+		Max:   jsonFloat(b.Max),
+		Last:  jsonFloat(b.Last),
+		SumSq: jsonFloat(b.SumSq),
+	}
+}
+
+// debugWindow encodes a Snapshot like its MarshalJSON with debugBuckets.
+type debugWindow struct {
+	Start    time.Time     `json:"start"`
+	Interval time.Duration `json:"interval"`
+	Buckets  []debugBucket `json:"buckets"`
+}
+
+type debugHistogramBucket struct {
+	UpperBound jsonFloat `json:"le"`
+	Count      int64     `json:"count"`
+	Sum        jsonFloat `json:"sum"`
+}
+
+// Dump describes every registered metric keyed by name and labels, such as
+// requests{path="/"}, for ad hoc inspection. Sliding windows include their buckets.
+func (r *Registry) Dump() map[string]interface{} {
+	dump := make(map[string]interface{})
+	r.Each(func(name string, labels Labels, metric interface{}) {
+		if v, ok := debugDescribe(metric); ok {
+			v.Labels = labels
+			dump[name+labels.String()] = v
+		}
+	})
+	return dump
+}
+
+func debugDescribe(metric interface{}) (*debugValue, bool) {
+	value := func(v float64) *jsonFloat {
+		f := jsonFloat(v)
+		return &f
+	}
+	switch m := metric.(type) {
+	case Gauge:
+		return &debugValue{Type: "gauge", Value: value(m.Value())}, true
+	case SlidingCounter:
+		var buckets []debugBucket
+		m.Reduce(func(b *Bucket) {
+			buckets = append(buckets, newDebugBucket(b))
+		})
+		return &debugValue{
+			Type: "sliding_counter",
+			Stats: map[string]jsonFloat{
+				"count":  jsonFloat(m.Count()),
+				"sum":    jsonFloat(m.Sum()),
+				"min":    jsonFloat(m.Min()),
+				"max":    jsonFloat(m.Max()),
+				"avg":    jsonFloat(m.Avg()),
+				"stddev": jsonFloat(m.StdDev()),
+				"rate":   jsonFloat(m.Rate()),
+			},
+			Buckets: buckets,
+		}, true
+	case ValueCounter:
+		return &debugValue{Type: "counter", Value: value(m.Value())}, true
+	case *SlidingWindow:
+		if !m.bucketed() {
+			return &debugValue{Type: "sliding_window"}, true
+		}
+		s := m.Snapshot()
+		window := &debugWindow{Start: s.start, Interval: s.interval, Buckets: make([]debugBucket, len(s.buckets))}
+		for i := range s.buckets {
+			window.Buckets[i] = newDebugBucket(&s.buckets[i])
+		}
+		return &debugValue{Type: "sliding_window", Window: window}, true
+	case Timer:
+		s := m.Snapshot()
+		return &debugValue{
+			Type: "timer",
+			Stats: map[string]jsonFloat{
+				"count": jsonFloat(s.Count),
+				"rate":  jsonFloat(s.Rate),
+				"mean":  jsonFloat(s.Mean.Seconds()),
+				"min":   jsonFloat(s.Min.Seconds()),
+				"max":   jsonFloat(s.Max.Seconds()),
+				"p50":   jsonFloat(s.Percentile(0.5).Seconds()),
+				"p99":   jsonFloat(s.Percentile(0.99).Seconds()),
+			},
+		}, true
+	case Sample:
+		s := m.Snapshot()
+		return &debugValue{
+			Type: "sample",
+			Stats: map[string]jsonFloat{
+				"count":  jsonFloat(s.Count),
+				"mean":   jsonFloat(s.Mean),
+				"min":    jsonFloat(s.Min),
+				"max":    jsonFloat(s.Max),
+				"stddev": jsonFloat(s.StdDev),
+				"p50":    jsonFloat(s.Quantile(0.5)),
+				"p99":    jsonFloat(s.Quantile(0.99)),
+			},
+		}, true
+	case Meter:
+		return &debugValue{
+			Type: "meter",
+			Stats: map[string]jsonFloat{
+				"count":  jsonFloat(m.Count()),
+				"mean":   jsonFloat(m.RateMean()),
+				"rate1":  jsonFloat(m.Rate1()),
+				"rate5":  jsonFloat(m.Rate5()),
+				"rate15": jsonFloat(m.Rate15()),
+			},
+		}, true
+	case Histogram:
+		hb := m.Buckets()
+		buckets := make([]debugHistogramBucket, len(hb))
+		var count int64
+		var sum float64
+		for i, b := range hb {
+			buckets[i] = debugHistogramBucket{UpperBound: jsonFloat(b.UpperBound), Count: b.Count, Sum: jsonFloat(b.Sum)}
+			count += b.Count
+			sum += b.Sum
+		}
+		return &debugValue{
+			Type: "histogram",
+			Stats: map[string]jsonFloat{
+				"count": jsonFloat(count),
+				"sum":   jsonFloat(sum),
+				"p50":   jsonFloat(bucketQuantile(hb, 0.5)),
+				"p99":   jsonFloat(bucketQuantile(hb, 0.99)),
+			},
+			Buckets: buckets,
+		}, true
+	}
+	return nil, false
+}
+
+// Publish exposes the registry as the expvar variable name, so its metrics show up
+// in /debug/vars. Like expvar.Publish it panics if name is already in use.
+func (r *Registry) Publish(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return r.Dump()
+	}))
+}
+
+// Handler returns an http.Handler that writes Dump as indented JSON, e.g.
+//
+//	http.Handle("/debug/metrics", metrics.Handler(metrics.DefaultRegistry))
+func Handler(r *Registry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(r.Dump()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
@@ -0,0 +1,123 @@
+package metrics
+
+import (
+	"encoding/json"
+	"expvar"
+	"math"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newDebugRegistry() *Registry {
+	r := NewRegistry()
+	g := NewGauge()
+	g.Set(math.Inf(1))
+	r.RegisterWithLabels("gauge", Labels{"host": "a"}, g)
+	c := NewCounter()
+	c.Add(2)
+	r.Register("counter", c)
+	sc := NewSlidingCounter(2, time.Second)
+	sc.Add(3)
+	r.Register("sliding_counter", sc)
+	w := NewSlidingWindow(3, time.Second)
+	w.Add(4)
+	r.Register("sliding_window", w)
+	h := NewHistogram(1)
+	h.Observe(5)
+	r.Register("histogram", h)
+	m := NewMeter()
+	m.Mark(6)
+	r.Register("meter", m)
+	tm := NewTimer(2, time.Second)
+	tm.UpdateDuration(time.Second)
+	r.Register("timer", tm)
+	s := NewExpDecaySample(10, DefaultSampleAlpha)
+	s.Update(7)
+	r.Register("sample", s)
+	return r
+}
+
+func TestRegistryPublish(t *testing.T) {
+	r := newDebugRegistry()
+	r.Publish("test_metrics")
+	v := expvar.Get("test_metrics")
+	assert.NotNil(t, v)
+	var dump map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(v.String()), &dump))
+	assert.Len(t, dump, 8)
+	assert.Panics(t, func() {
+		r.Publish("test_metrics")
+	})
+}
+
+func TestDumpCustomAggregator(t *testing.T) {
+	r := NewRegistry()
+	w := NewSlidingWindow(2, time.Second, WithAggregator(func() Aggregator {
+		return new(maxAggregator)
+	}))
+	w.Add(1)
+	r.Register("max", w)
+	assert.Equal(t, map[string]interface{}{"max": &debugValue{Type: "sliding_window", Labels: Labels{}}}, r.Dump())
+	rec := httptest.NewRecorder()
+	Handler(r).ServeHTTP(rec, httptest.NewRequest("GET", "/debug/metrics", nil))
+	assert.Equal(t, 200, rec.Code)
+}
+
+func TestHandlerInfinity(t *testing.T) {
+	r := NewRegistry()
+	sc := NewSlidingCounter(2, time.Second)
+	sc.Add(math.Inf(1))
+	r.Register("sliding_counter", sc)
+	w := NewSlidingWindow(2, time.Second)
+	w.Add(math.Inf(-1))
+	r.Register("sliding_window", w)
+	rec := httptest.NewRecorder()
+	Handler(r).ServeHTTP(rec, httptest.NewRequest("GET", "/debug/metrics", nil))
+	assert.Equal(t, 200, rec.Code)
+
+	var dump map[string]struct {
+		Buckets []map[string]interface{}
+		Window  struct {
+			Buckets []map[string]interface{}
+		}
+	}
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &dump))
+	assert.Equal(t, "+Inf", dump["sliding_counter"].Buckets[1]["max"])
+	assert.Equal(t, "-Inf", dump["sliding_window"].Window.Buckets[1]["last"])
+	assert.Equal(t, "+Inf", dump["sliding_window"].Window.Buckets[1]["sumsq"])
+}
+
+func TestHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Handler(newDebugRegistry()).ServeHTTP(rec, httptest.NewRequest("GET", "/debug/metrics", nil))
+	assert.Equal(t, 200, rec.Code)
+
+	var dump map[string]struct {
+		Type    string
+		Labels  map[string]string
+		Value   interface{}
+		Stats   map[string]interface{}
+		Buckets []map[string]interface{}
+		Window  struct {
+			Buckets []Bucket
+		}
+	}
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &dump))
+	assert.Equal(t, "gauge", dump[`gauge{host="a"}`].Type)
+	assert.Equal(t, "+Inf", dump[`gauge{host="a"}`].Value)
+	assert.Equal(t, map[string]string{"host": "a"}, dump[`gauge{host="a"}`].Labels)
+	assert.Equal(t, 2.0, dump["counter"].Value)
+	assert.Equal(t, 3.0, dump["sliding_counter"].Stats["sum"])
+	assert.Len(t, dump["sliding_counter"].Buckets, 2)
+	assert.Equal(t, "sliding_window", dump["sliding_window"].Type)
+	assert.Len(t, dump["sliding_window"].Window.Buckets, 3)
+	assert.Equal(t, 4.0, dump["sliding_window"].Window.Buckets[2].Sum)
+	assert.Equal(t, "+Inf", dump["histogram"].Buckets[1]["le"])
+	assert.Equal(t, 1.0, dump["histogram"].Stats["count"])
+	assert.Equal(t, 6.0, dump["meter"].Stats["count"])
+	assert.Equal(t, 1.0, dump["timer"].Stats["mean"])
+	assert.Equal(t, 7.0, dump["sample"].Stats["p99"])
+}
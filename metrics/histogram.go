@@ -0,0 +1,159 @@
+package metrics
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+var _ Histogram = (*histogram)(nil)
+
+// DefBuckets are the default histogram upper bounds, tailored to measure
+// request latencies in seconds.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// HistogramBucket counts the samples in (previous UpperBound, UpperBound].
+type HistogramBucket struct {
+	UpperBound float64
+	Count      int64
+	Sum        float64
+	Exemplar   *Exemplar // the latest exemplar observed in the bucket, nil if none
+}
+
+type histogram struct {
+	bounds  []float64 // finite upper bounds, never change after construction
+	mu      sync.Mutex
+	buckets []HistogramBucket // the last bucket has an upper bound of +Inf
+	count   int64
+	sum     float64
+}
+
+// NewHistogram creates a histogram with the given upper bounds, DefBuckets are used
+// when none are given. A +Inf bucket is always appended.
+func NewHistogram(bounds ...float64) Histogram {
+	bounds = finiteBounds(bounds)
+	h := &histogram{bounds: bounds, buckets: make([]HistogramBucket, len(bounds)+1)}
+	for i, b := range bounds {
+		h.buckets[i].UpperBound = b
+	}
+	h.buckets[len(bounds)].UpperBound = math.Inf(1)
+	return h
+}
+
+// finiteBounds validates histogram upper bounds and drops a trailing +Inf,
+// it returns DefBuckets for empty bounds.
+func finiteBounds(bounds []float64) []float64 {
+	if len(bounds) == 0 {
+		return DefBuckets
+	}
+	if !sort.Float64sAreSorted(bounds) {
+		panic("histogram bounds must be sorted in increasing order")
+	}
+	for i := 1; i < len(bounds); i++ {
+		if bounds[i] == bounds[i-1] {
+			panic("histogram bounds must be unique")
+		}
+	}
+	if math.IsInf(bounds[len(bounds)-1], 1) {
+		bounds = bounds[:len(bounds)-1]
+	}
+	return bounds
+}
+
+func (h *histogram) Observe(v float64) {
+	h.observe(v, nil)
+}
+
+// ObserveWithExemplar panics if the labels are longer than MaxExemplarRunes.
+func (h *histogram) ObserveWithExemplar(v float64, labels Labels) {
+	runes := 0
+	copied := make(Labels, len(labels))
+	for k, v := range labels {
+		runes += utf8.RuneCountInString(k) + utf8.RuneCountInString(v)
+		copied[k] = v
+	}
+	if runes > MaxExemplarRunes {
+		panic("exemplar labels must not be longer than 128 runes")
+	}
+	h.observe(v, &Exemplar{Labels: copied, Value: v, Timestamp: time.Now()})
+}
+
+func (h *histogram) observe(v float64, e *Exemplar) {
+	i := sort.SearchFloat64s(h.bounds, v)
+	h.mu.Lock()
+	b := &h.buckets[i]
+	b.Count++
+	b.Sum += v
+	if e != nil {
+		b.Exemplar = e
+	}
+	h.count++
+	h.sum += v
+	h.mu.Unlock()
+}
+
+func (h *histogram) Count() int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.count
+}
+
+func (h *histogram) Sum() float64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.sum
+}
+
+// Buckets returns a copy of the buckets, in increasing upper bound order.
+// Exemplars are shared and must not be modified.
+func (h *histogram) Buckets() []HistogramBucket {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	buckets := make([]HistogramBucket, len(h.buckets))
+	copy(buckets, h.buckets)
+	return buckets
+}
+
+// Quantile estimates the q-quantile by linear interpolation inside the bucket that
+// holds it, it returns 0 for an empty histogram.
+func (h *histogram) Quantile(q float64) float64 {
+	return bucketQuantile(h.Buckets(), q)
+}
+
+func bucketQuantile(buckets []HistogramBucket, q float64) float64 {
+	var total int64
+	for _, b := range buckets {
+		total += b.Count
+	}
+	if total == 0 {
+		return 0
+	}
+	if q < 0 {
+		q = 0
+	} else if q > 1 {
+		q = 1
+	}
+
+	rank := q * float64(total)
+	var cum int64
+	for i, b := range buckets {
+		if b.Count == 0 || float64(cum+b.Count) < rank {
+			cum += b.Count
+			continue
+		}
+		lower := 0.0
+		if i > 0 {
+			lower = buckets[i-1].UpperBound
+		}
+		if math.IsInf(b.UpperBound, 1) {
+			return lower
+		}
+		if i == 0 && b.UpperBound <= 0 {
+			return b.UpperBound
+		}
+		return lower + (b.UpperBound-lower)*(rank-float64(cum))/float64(b.Count)
+	}
+	return buckets[len(buckets)-1].UpperBound
+}
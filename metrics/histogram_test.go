@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewHistogram(t *testing.T) {
+	h := NewHistogram()
+	assert.Len(t, h.Buckets(), len(DefBuckets)+1)
+	assert.Len(t, NewHistogram(1, 2, math.Inf(1)).Buckets(), 3)
+	assert.Panics(t, func() {
+		NewHistogram(2, 1)
+	})
+	assert.Panics(t, func() {
+		NewHistogram(1, 1)
+	})
+}
+
+func TestHistogramObserve(t *testing.T) {
+	h := NewHistogram(1, 2, 4)
+	for _, v := range []float64{0.5, 1, 1.5, 3, 10} {
+		h.Observe(v)
+	}
+	assert.Equal(t, int64(5), h.Count())
+	assert.Equal(t, 16.0, h.Sum())
+	assert.Equal(t, []HistogramBucket{
+		{UpperBound: 1, Count: 2, Sum: 1.5},
+		{UpperBound: 2, Count: 1, Sum: 1.5},
+		{UpperBound: 4, Count: 1, Sum: 3},
+		{UpperBound: math.Inf(1), Count: 1, Sum: 10},
+	}, h.Buckets())
+}
+
+func TestHistogramObserveWithExemplar(t *testing.T) {
+	h := NewHistogram(1, 2)
+	labels := Labels{"trace_id": "a"}
+	h.ObserveWithExemplar(0.5, labels)
+	labels["trace_id"] = "b"
+	h.ObserveWithExemplar(0.7, labels)
+	h.Observe(0.9)
+	h.ObserveWithExemplar(3, Labels{"trace_id": "c"})
+
+	buckets := h.Buckets()
+	assert.Equal(t, int64(3), buckets[0].Count)
+	assert.Equal(t, Labels{"trace_id": "b"}, buckets[0].Exemplar.Labels)
+	assert.Equal(t, 0.7, buckets[0].Exemplar.Value)
+	assert.WithinDuration(t, time.Now(), buckets[0].Exemplar.Timestamp, time.Second)
+	assert.Nil(t, buckets[1].Exemplar)
+	assert.Equal(t, 3.0, buckets[2].Exemplar.Value)
+	assert.Equal(t, int64(4), h.Count())
+
+	h.ObserveWithExemplar(1, Labels{"id": strings.Repeat("x", MaxExemplarRunes-2)})
+	assert.Panics(t, func() {
+		h.ObserveWithExemplar(1, Labels{"id": strings.Repeat("x", MaxExemplarRunes-1)})
+	})
+}
+
+func TestHistogramQuantile(t *testing.T) {
+	h := NewHistogram(1, 2, 4)
+	assert.Equal(t, 0.0, h.Quantile(0.5))
+	for i := 0; i < 50; i++ {
+		h.Observe(0.5)
+		h.Observe(1.5)
+	}
+	assert.Equal(t, 1.0, h.Quantile(0.5))
+	assert.Equal(t, 1.5, h.Quantile(0.75))
+	assert.Equal(t, 2.0, h.Quantile(1))
+	h.Observe(100)
+	assert.Equal(t, 4.0, h.Quantile(1))
+}
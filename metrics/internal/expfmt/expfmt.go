@@ -0,0 +1,37 @@
+// Package expfmt holds the formatting shared by the Prometheus exporters of metrics.
+package expfmt
+
+import (
+	"math"
+	"strconv"
+)
+
+// SummaryQuantiles are reported for timers, like a Prometheus summary.
+var SummaryQuantiles = []float64{0.5, 0.9, 0.99}
+
+// FormatFloat formats v as Prometheus and OpenMetrics expect, such as +Inf for le labels.
+func FormatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// SanitizeName replaces the characters not allowed in Prometheus names with '_',
+// colons are only allowed in metric names.
+func SanitizeName(s string, colon bool) string {
+	b := []byte(s)
+	for i, c := range b {
+		valid := c == '_' || (colon && c == ':') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') ||
+			(i > 0 && c >= '0' && c <= '9')
+		if !valid {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
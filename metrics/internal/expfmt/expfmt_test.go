@@ -0,0 +1,21 @@
+package expfmt
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatFloat(t *testing.T) {
+	assert.Equal(t, "+Inf", FormatFloat(math.Inf(1)))
+	assert.Equal(t, "-Inf", FormatFloat(math.Inf(-1)))
+	assert.Equal(t, "NaN", FormatFloat(math.NaN()))
+	assert.Equal(t, "0.99", FormatFloat(0.99))
+	assert.Equal(t, "1e+06", FormatFloat(1e6))
+}
+
+func TestSanitizeName(t *testing.T) {
+	assert.Equal(t, "window_latency:p99", SanitizeName("window.latency:p99", true))
+	assert.Equal(t, "_xx_rate_", SanitizeName("2xx-rate:", false))
+}
@@ -0,0 +1,26 @@
+// Package metricstest holds the helpers shared by the tests of the metrics exporters.
+package metricstest
+
+import (
+	"time"
+
+	"github.com/zjbztianya/go-misc/metrics"
+)
+
+// lastAggregator keeps the last sample, a custom aggregator the exporters skip.
+type lastAggregator struct {
+	v float64
+}
+
+func (l *lastAggregator) Add(v float64)              { l.v = v }
+func (l *lastAggregator) Reset()                     { l.v = 0 }
+func (l *lastAggregator) Merge(o metrics.Aggregator) { l.v = o.(*lastAggregator).v }
+
+// NewLastWindow returns a sliding window of a custom aggregator holding a sample.
+func NewLastWindow() *metrics.SlidingWindow {
+	w := metrics.NewSlidingWindow(2, time.Second, metrics.WithAggregator(func() metrics.Aggregator {
+		return new(lastAggregator)
+	}))
+	w.Add(1)
+	return w
+}
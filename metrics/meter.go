@@ -0,0 +1,168 @@
+package metrics
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var _ Meter = (*meter)(nil)
+
+// DefaultTickInterval is how often the moving averages of a Meter decay.
+const DefaultTickInterval = 5 * time.Second
+
+// Meter measures the rate of events per second: a mean rate since creation and
+// exponentially weighted moving averages over several horizons, like Unix load averages.
+type Meter interface {
+	Mark(n int64)
+	Count() int64
+	// Rate returns the moving average for a configured horizon, 0 for other horizons.
+	Rate(horizon time.Duration) float64
+	Rate1() float64
+	Rate5() float64
+	Rate15() float64
+	RateMean() float64
+}
+
+type MeterOption func(*meter)
+
+// WithHorizons sets the moving average horizons, 1, 5 and 15 minutes by default.
+func WithHorizons(horizons ...time.Duration) MeterOption {
+	return func(m *meter) {
+		m.ewmas = m.ewmas[:0]
+		for _, h := range horizons {
+			m.ewmas = append(m.ewmas, &ewma{horizon: h})
+		}
+	}
+}
+
+// WithTickInterval sets how often the moving averages decay.
+func WithTickInterval(interval time.Duration) MeterOption {
+	return func(m *meter) {
+		m.tick = interval
+	}
+}
+
+// meter ticks lazily: the moving averages catch up with the elapsed ticks whenever the
+// meter is marked or read, so no background goroutine is needed.
+type meter struct {
+	count     int64 // atomic
+	uncounted int64 // atomic, events since the last tick
+	lastTick  int64 // atomic, unix nanoseconds
+
+	mu    sync.Mutex // guards ewmas
+	ewmas []*ewma
+	tick  time.Duration
+	start time.Time
+	now   func() time.Time
+}
+
+func NewMeter(opts ...MeterOption) Meter {
+	return newMeter(time.Now, opts...)
+}
+
+func newMeter(now func() time.Time, opts ...MeterOption) *meter {
+	m := &meter{tick: DefaultTickInterval, now: now}
+	WithHorizons(time.Minute, 5*time.Minute, 15*time.Minute)(m)
+	for _, opt := range opts {
+		opt(m)
+	}
+	if m.tick <= 0 {
+		panic("meter tick interval must greater than 0")
+	}
+	for _, e := range m.ewmas {
+		if e.horizon <= 0 {
+			panic("meter horizon must greater than 0")
+		}
+		e.alpha = 1 - math.Exp(-float64(m.tick)/float64(e.horizon))
+	}
+	m.start = now()
+	m.lastTick = m.start.UnixNano()
+	return m
+}
+
+func (m *meter) Mark(n int64) {
+	m.tickIfNecessary()
+	atomic.AddInt64(&m.count, n)
+	atomic.AddInt64(&m.uncounted, n)
+}
+
+func (m *meter) Count() int64 {
+	return atomic.LoadInt64(&m.count)
+}
+
+func (m *meter) Rate(horizon time.Duration) float64 {
+	m.tickIfNecessary()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, e := range m.ewmas {
+		if e.horizon == horizon {
+			return e.rate
+		}
+	}
+	return 0
+}
+
+func (m *meter) Rate1() float64 {
+	return m.Rate(time.Minute)
+}
+
+func (m *meter) Rate5() float64 {
+	return m.Rate(5 * time.Minute)
+}
+
+func (m *meter) Rate15() float64 {
+	return m.Rate(15 * time.Minute)
+}
+
+func (m *meter) RateMean() float64 {
+	elapsed := m.now().Sub(m.start)
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(m.Count()) / elapsed.Seconds()
+}
+
+func (m *meter) tickIfNecessary() {
+	now := m.now().UnixNano()
+	if now-atomic.LoadInt64(&m.lastTick) < int64(m.tick) {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	last := atomic.LoadInt64(&m.lastTick)
+	ticks := (now - last) / int64(m.tick)
+	if ticks <= 0 {
+		// another goroutine ticked meanwhile
+		return
+	}
+	atomic.StoreInt64(&m.lastTick, last+ticks*int64(m.tick))
+	uncounted := atomic.SwapInt64(&m.uncounted, 0)
+	for _, e := range m.ewmas {
+		e.update(uncounted, m.tick, ticks)
+	}
+}
+
+// ewma is an exponentially weighted moving average of a per second rate.
+type ewma struct {
+	horizon time.Duration
+	alpha   float64
+	rate    float64
+	init    bool
+}
+
+// update folds count events seen during the first of ticks intervals, the other ticks saw none.
+func (e *ewma) update(count int64, interval time.Duration, ticks int64) {
+	instant := float64(count) / interval.Seconds()
+	if e.init {
+		e.rate += e.alpha * (instant - e.rate)
+	} else {
+		e.rate = instant
+		e.init = true
+	}
+	if ticks > 1 {
+		e.rate *= math.Pow(1-e.alpha, float64(ticks-1))
+	}
+}
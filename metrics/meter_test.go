@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.t
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+func TestNewMeter(t *testing.T) {
+	m := NewMeter()
+	m.Mark(3)
+	assert.Equal(t, int64(3), m.Count())
+	assert.Equal(t, 0.0, m.Rate1())
+	assert.Panics(t, func() {
+		NewMeter(WithTickInterval(0))
+	})
+}
+
+func TestMeterRates(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1600000000, 0)}
+	m := newMeter(clock.now)
+	m.Mark(5)
+	clock.advance(DefaultTickInterval)
+	// the first tick initializes every average to the instant rate
+	assert.Equal(t, 1.0, m.Rate1())
+	assert.Equal(t, 1.0, m.Rate5())
+	assert.Equal(t, 1.0, m.Rate15())
+	assert.Equal(t, 1.0, m.RateMean())
+
+	// one minute without events decays the 1 minute average by 1/e
+	clock.advance(time.Minute)
+	assert.InDelta(t, math.Exp(-1), m.Rate1(), 1e-9)
+	assert.InDelta(t, math.Exp(-0.2), m.Rate5(), 1e-9)
+	assert.InDelta(t, math.Exp(-1.0/15), m.Rate15(), 1e-9)
+}
+
+func TestMeterSteadyRate(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1600000000, 0)}
+	m := newMeter(clock.now, WithHorizons(10*time.Second), WithTickInterval(time.Second))
+	for i := 0; i < 600; i++ {
+		m.Mark(100)
+		clock.advance(100 * time.Millisecond)
+	}
+	assert.InDelta(t, 1000, m.Rate(10*time.Second), 1e-6)
+	assert.InDelta(t, 1000, m.RateMean(), 1e-6)
+	assert.Equal(t, 0.0, m.Rate1())
+}
+
+func BenchmarkMeterMark(b *testing.B) {
+	m := NewMeter()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.Mark(1)
+	}
+}
@@ -1,11 +1,20 @@
 package metrics
 
+import "time"
+
 // Counter is metrics counter.
 type Counter interface {
 	Inc()
 	Add(delta float64)
 }
 
+// ValueCounter is a Counter whose value can be read, such as the counters of NewCounter.
+// Exporters only report the counters implementing it.
+type ValueCounter interface {
+	Counter
+	Value() float64
+}
+
 // Gauge is metrics gauge.
 type Gauge interface {
 	Set(value float64)
@@ -15,3 +24,26 @@ type Gauge interface {
 	Sub(delta float64)
 	Value() float64
 }
+
+// Histogram is metrics histogram, samples are counted in buckets with fixed upper bounds.
+type Histogram interface {
+	Observe(v float64)
+	// ObserveWithExemplar observes v and keeps it with labels, such as a trace ID,
+	// as the exemplar of its bucket, replacing the previous one.
+	ObserveWithExemplar(v float64, labels Labels)
+	Count() int64
+	Sum() float64
+	Buckets() []HistogramBucket
+	Quantile(q float64) float64
+}
+
+// MaxExemplarRunes is the OpenMetrics limit of the combined length of the label
+// names and values of an exemplar.
+const MaxExemplarRunes = 128
+
+// Exemplar is an observation linked to external data such as the trace of a request.
+type Exemplar struct {
+	Labels    Labels
+	Value     float64
+	Timestamp time.Time
+}
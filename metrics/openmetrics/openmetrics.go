@@ -0,0 +1,198 @@
+package openmetrics
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zjbztianya/go-misc/metrics"
+	"github.com/zjbztianya/go-misc/metrics/internal/expfmt"
+)
+
+// ContentType is the media type of the OpenMetrics text exposition format.
+const ContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// family is a metric family, all the samples sharing a name and a type.
+type family struct {
+	name    string
+	typ     string
+	samples bytes.Buffer
+}
+
+// Write writes the metrics of reg in the OpenMetrics text format, families sorted by name.
+// Gauges become gauges and counters and meters counters, the latter reporting their count.
+// Sliding counters and sliding windows become <name>_sum and <name>_count gauges, timers
+// summaries in seconds and histograms histograms, with the latest exemplar of each bucket.
+func Write(w io.Writer, reg *metrics.Registry) error {
+	families := make(map[string]*family)
+	get := func(name, typ string) *family {
+		name = expfmt.SanitizeName(name, true)
+		f, ok := families[name]
+		if !ok {
+			f = &family{name: name, typ: typ}
+			families[name] = f
+		}
+		return f
+	}
+
+	reg.Each(func(name string, labels metrics.Labels, metric interface{}) {
+		switch m := metric.(type) {
+		case metrics.Gauge:
+			f := get(name, "gauge")
+			writeSample(&f.samples, f.name, labels, nil, m.Value())
+		case metrics.SlidingCounter:
+			f := get(name+"_sum", "gauge")
+			writeSample(&f.samples, f.name, labels, nil, m.Sum())
+			f = get(name+"_count", "gauge")
+			writeSample(&f.samples, f.name, labels, nil, m.Count())
+		case metrics.ValueCounter:
+			f := get(strings.TrimSuffix(name, "_total"), "counter")
+			writeSample(&f.samples, f.name+"_total", labels, nil, m.Value())
+		case metrics.Meter:
+			f := get(strings.TrimSuffix(name, "_total"), "counter")
+			writeSample(&f.samples, f.name+"_total", labels, nil, float64(m.Count()))
+		case *metrics.SlidingWindow:
+			// windows of custom aggregators have no sum and count to export
+			total, ok := m.Aggregate().(*metrics.Bucket)
+			if !ok {
+				return
+			}
+			f := get(name+"_sum", "gauge")
+			writeSample(&f.samples, f.name, labels, nil, total.Sum)
+			f = get(name+"_count", "gauge")
+			writeSample(&f.samples, f.name, labels, nil, float64(total.Count))
+		case metrics.Timer:
+			s := m.Snapshot()
+			f := get(name, "summary")
+			for _, q := range expfmt.SummaryQuantiles {
+				writeSample(&f.samples, f.name, labels, &label{"quantile", expfmt.FormatFloat(q)}, s.Percentile(q).Seconds())
+			}
+			writeSample(&f.samples, f.name+"_sum", labels, nil, s.Sum.Seconds())
+			writeSample(&f.samples, f.name+"_count", labels, nil, float64(s.Count))
+		case metrics.Histogram:
+			f := get(name, "histogram")
+			var cum int64
+			var sum float64
+			for _, b := range m.Buckets() {
+				cum += b.Count
+				sum += b.Sum
+				writeExemplarSample(&f.samples, f.name+"_bucket", labels, &label{"le", expfmt.FormatFloat(b.UpperBound)}, float64(cum), b.Exemplar)
+			}
+			writeSample(&f.samples, f.name+"_sum", labels, nil, sum)
+			writeSample(&f.samples, f.name+"_count", labels, nil, float64(cum))
+		}
+	})
+
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var buf bytes.Buffer
+	for _, name := range names {
+		f := families[name]
+		buf.WriteString("# TYPE ")
+		buf.WriteString(f.name)
+		buf.WriteByte(' ')
+		buf.WriteString(f.typ)
+		buf.WriteByte('\n')
+		buf.Write(f.samples.Bytes())
+	}
+	buf.WriteString("# EOF\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// Handler returns an http.Handler that writes the metrics of reg, e.g.
+//
+//	http.Handle("/metrics", openmetrics.Handler(metrics.DefaultRegistry))
+func Handler(reg *metrics.Registry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var buf bytes.Buffer
+		if err := Write(&buf, reg); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", ContentType)
+		w.Write(buf.Bytes())
+	})
+}
+
+type label struct {
+	name, value string
+}
+
+// writeSample writes a sample line such as name{k="v",le="1"} 3.
+func writeSample(buf *bytes.Buffer, name string, labels metrics.Labels, extra *label, v float64) {
+	writeExemplarSample(buf, name, labels, extra, v, nil)
+}
+
+// writeExemplarSample writes a sample line followed by exemplar e, if not nil, such as
+// name{le="1"} 3 # {trace_id="abc"} 0.43 1600000000.123.
+func writeExemplarSample(buf *bytes.Buffer, name string, labels metrics.Labels, extra *label, v float64, e *metrics.Exemplar) {
+	buf.WriteString(name)
+	writeLabels(buf, labels, extra)
+	buf.WriteByte(' ')
+	buf.WriteString(expfmt.FormatFloat(v))
+	if e == nil {
+		buf.WriteByte('\n')
+		return
+	}
+	buf.WriteString(" # ")
+	if len(e.Labels) == 0 {
+		buf.WriteString("{}")
+	} else {
+		writeLabels(buf, e.Labels, nil)
+	}
+	buf.WriteByte(' ')
+	buf.WriteString(expfmt.FormatFloat(e.Value))
+	if !e.Timestamp.IsZero() {
+		buf.WriteByte(' ')
+		ms := e.Timestamp.UnixNano() / int64(time.Millisecond)
+		buf.WriteString(strconv.FormatFloat(float64(ms)/1e3, 'f', -1, 64))
+	}
+	buf.WriteByte('\n')
+}
+
+// writeLabels writes labels and extra sorted by name, extra is always last.
+func writeLabels(buf *bytes.Buffer, labels metrics.Labels, extra *label) {
+	if len(labels) == 0 && extra == nil {
+		return
+	}
+	buf.WriteByte('{')
+	for i, name := range labels.Names() {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		writeLabel(buf, expfmt.SanitizeName(name, false), labels[name])
+	}
+	if extra != nil {
+		if len(labels) > 0 {
+			buf.WriteByte(',')
+		}
+		writeLabel(buf, extra.name, extra.value)
+	}
+	buf.WriteByte('}')
+}
+
+func writeLabel(buf *bytes.Buffer, name, value string) {
+	buf.WriteString(name)
+	buf.WriteString(`="`)
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '\\':
+			buf.WriteString(`\\`)
+		case '"':
+			buf.WriteString(`\"`)
+		case '\n':
+			buf.WriteString(`\n`)
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	buf.WriteByte('"')
+}
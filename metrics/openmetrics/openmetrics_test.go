@@ -0,0 +1,97 @@
+package openmetrics
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zjbztianya/go-misc/metrics"
+	"github.com/zjbztianya/go-misc/metrics/internal/metricstest"
+)
+
+func TestWrite(t *testing.T) {
+	reg := metrics.NewRegistry()
+	g := metrics.NewGauge()
+	g.Set(-1.5)
+	reg.RegisterWithLabels("temperature", metrics.Labels{"room": `a"b`}, g)
+	c := metrics.NewCounter()
+	c.Add(3)
+	reg.RegisterWithLabels("requests_total", metrics.Labels{"path": "/login"}, c)
+	m := metrics.NewMeter()
+	m.Mark(7)
+	reg.Register("events", m)
+	sc := metrics.NewSlidingCounter(2, time.Second)
+	sc.Add(2)
+	reg.Register("queue", sc)
+	tm := metrics.NewTimer(10, time.Second, 0.1)
+	tm.UpdateDuration(50 * time.Millisecond)
+	reg.Register("call", tm)
+	h := metrics.NewHistogram(1, 2)
+	h.Observe(0.5)
+	h.ObserveWithExemplar(1.5, metrics.Labels{"trace_id": "abc"})
+	reg.RegisterWithLabels("latency", metrics.Labels{"path": "/"}, h)
+	// skipped, a custom aggregator has no sum and count
+	reg.Register("last", metricstest.NewLastWindow())
+
+	var buf bytes.Buffer
+	assert.Nil(t, Write(&buf, reg))
+	out := regexp.MustCompile(`1\.5 \d+(\.\d+)?\n`).ReplaceAllString(buf.String(), "1.5 <ts>\n")
+	assert.Equal(t, `# TYPE call summary
+call{quantile="0.5"} 0.05
+call{quantile="0.9"} 0.09
+call{quantile="0.99"} 0.099
+call_sum 0.05
+call_count 1
+# TYPE events counter
+events_total 7
+# TYPE latency histogram
+latency_bucket{path="/",le="1"} 1
+latency_bucket{path="/",le="2"} 2 # {trace_id="abc"} 1.5 <ts>
+latency_bucket{path="/",le="+Inf"} 2
+latency_sum{path="/"} 2
+latency_count{path="/"} 2
+# TYPE queue_count gauge
+queue_count 1
+# TYPE queue_sum gauge
+queue_sum 2
+# TYPE requests counter
+requests_total{path="/login"} 3
+# TYPE temperature gauge
+temperature{room="a\"b"} -1.5
+# EOF
+`, out)
+}
+
+func TestWriteExemplarTimestamp(t *testing.T) {
+	var buf bytes.Buffer
+	writeExemplarSample(&buf, "x_bucket", nil, &label{"le", "1"}, 1, &metrics.Exemplar{
+		Value:     0.25,
+		Timestamp: time.Unix(1600000000, 123456789),
+	})
+	assert.Equal(t, "x_bucket{le=\"1\"} 1 # {} 0.25 1600000000.123\n", buf.String())
+}
+
+func TestWriteFamilies(t *testing.T) {
+	// a family is written once even if other names sort between its label sets
+	reg := metrics.NewRegistry()
+	reg.Register("a", metrics.NewGauge())
+	reg.Register("a_b", metrics.NewGauge())
+	reg.RegisterWithLabels("a", metrics.Labels{"k": "v"}, metrics.NewGauge())
+
+	var buf bytes.Buffer
+	assert.Nil(t, Write(&buf, reg))
+	assert.Equal(t, "# TYPE a gauge\na 0\na{k=\"v\"} 0\n# TYPE a_b gauge\na_b 0\n# EOF\n", buf.String())
+}
+
+func TestHandler(t *testing.T) {
+	reg := metrics.NewRegistry()
+	reg.Register("bad-name", metrics.NewGauge())
+	rec := httptest.NewRecorder()
+	Handler(reg).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, 200, rec.Code)
+	assert.Equal(t, ContentType, rec.Header().Get("Content-Type"))
+	assert.Equal(t, "# TYPE bad_name gauge\nbad_name 0\n# EOF\n", rec.Body.String())
+}
@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// userHZ is the unit of the cpu times in /proc/self/stat, sysconf(_SC_CLK_TCK)
+// is 100 on every common Linux platform.
+const userHZ = 100
+
+func readProcessStats() (processStats, error) {
+	var ps processStats
+	stat, err := ioutil.ReadFile("/proc/self/stat")
+	if err != nil {
+		return ps, err
+	}
+	if err = parseProcStat(stat, &ps); err != nil {
+		return ps, err
+	}
+
+	fds, err := ioutil.ReadDir("/proc/self/fd")
+	if err != nil {
+		return ps, err
+	}
+	ps.openFDs = float64(len(fds))
+
+	limits, err := os.Open("/proc/self/limits")
+	if err != nil {
+		return ps, err
+	}
+	defer limits.Close()
+	ps.maxFDs, err = parseMaxOpenFiles(bufio.NewScanner(limits))
+	return ps, err
+}
+
+// parseProcStat reads the cpu times and memory sizes of /proc/[pid]/stat, see proc(5).
+func parseProcStat(stat []byte, ps *processStats) error {
+	// the command name may contain spaces and parentheses, the fields start after the last ')'
+	i := bytes.LastIndexByte(stat, ')')
+	if i < 0 {
+		return errors.New("malformed /proc/self/stat")
+	}
+	// fields[0] is field 3 (state) in proc(5)
+	fields := strings.Fields(string(stat[i+1:]))
+	if len(fields) < 22 {
+		return errors.New("malformed /proc/self/stat")
+	}
+	var values [4]float64
+	for j, field := range []int{14, 15, 23, 24} { // utime, stime, vsize, rss
+		v, err := strconv.ParseFloat(fields[field-3], 64)
+		if err != nil {
+			return err
+		}
+		values[j] = v
+	}
+	ps.cpuSeconds = (values[0] + values[1]) / userHZ
+	ps.vsizeBytes = values[2]
+	ps.rssBytes = values[3] * float64(os.Getpagesize())
+	return nil
+}
+
+// parseMaxOpenFiles reads the soft limit of open files from /proc/[pid]/limits.
+func parseMaxOpenFiles(s *bufio.Scanner) (float64, error) {
+	for s.Scan() {
+		line := s.Text()
+		if !strings.HasPrefix(line, "Max open files") {
+			continue
+		}
+		fields := strings.Fields(strings.TrimPrefix(line, "Max open files"))
+		if len(fields) == 0 {
+			break
+		}
+		if fields[0] == "unlimited" {
+			return 0, nil
+		}
+		return strconv.ParseFloat(fields[0], 64)
+	}
+	if err := s.Err(); err != nil {
+		return 0, err
+	}
+	return 0, errors.New("max open files not found in /proc/self/limits")
+}
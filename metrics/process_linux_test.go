@@ -0,0 +1,31 @@
+package metrics
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseProcStat(t *testing.T) {
+	stat := "6905 (my (odd) cmd) R 6901 6905 6901 0 -1 4194304 80 0 0 0 150 50 0 0 20 0 1 0 78612 2703360 313 " +
+		"18446744073709551615 93995895959552 93995895979433 140733516295456 0 0 0 0 0 0 0 0 0 17 0 0 0 0 0 0"
+	var ps processStats
+	assert.Nil(t, parseProcStat([]byte(stat), &ps))
+	assert.Equal(t, 2.0, ps.cpuSeconds)
+	assert.Equal(t, 2703360.0, ps.vsizeBytes)
+	assert.Equal(t, float64(313*os.Getpagesize()), ps.rssBytes)
+	assert.NotNil(t, parseProcStat([]byte("6905 (cat"), &ps))
+}
+
+func TestParseMaxOpenFiles(t *testing.T) {
+	limits := "Limit                     Soft Limit           Hard Limit           Units\n" +
+		"Max open files            1024                 4096                 files\n"
+	v, err := parseMaxOpenFiles(bufio.NewScanner(strings.NewReader(limits)))
+	assert.Nil(t, err)
+	assert.Equal(t, 1024.0, v)
+	_, err = parseMaxOpenFiles(bufio.NewScanner(strings.NewReader("")))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+// +build !linux
+
+package metrics
+
+import "errors"
+
+func readProcessStats() (processStats, error) {
+	return processStats{}, errors.New("process metrics are only supported on linux")
+}
@@ -0,0 +1,122 @@
+package metrics
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Labels are the dimensions of a registered metric, such as {"path": "/login"}.
+type Labels map[string]string
+
+// String formats the labels as {k1="v1",k2="v2"} sorted by name.
+func (l Labels) String() string {
+	if len(l) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteByte('{')
+	for i, name := range l.Names() {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(name)
+		sb.WriteString(`="`)
+		sb.WriteString(l[name])
+		sb.WriteByte('"')
+	}
+	sb.WriteByte('}')
+	return sb.String()
+}
+
+// Names returns the sorted label names.
+func (l Labels) Names() []string {
+	names := make([]string, 0, len(l))
+	for name := range l {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DefaultRegistry is the registry used by package level exporters.
+var DefaultRegistry = NewRegistry()
+
+var errDuplicateMetric = errors.New("duplicate metric")
+
+type entry struct {
+	name   string
+	labels Labels
+	metric interface{}
+}
+
+// Registry holds named metrics, such as ValueCounter, Gauge, Histogram, SlidingCounter
+// and *SlidingWindow, so exporters can walk them. It is safe for concurrent use.
+type Registry struct {
+	mu      sync.RWMutex
+	entries map[string]*entry
+}
+
+func NewRegistry() *Registry {
+	return &Registry{entries: make(map[string]*entry)}
+}
+
+func (r *Registry) Register(name string, metric interface{}) error {
+	return r.RegisterWithLabels(name, nil, metric)
+}
+
+// RegisterWithLabels registers metric under name and labels, a name may be used
+// several times with different labels.
+func (r *Registry) RegisterWithLabels(name string, labels Labels, metric interface{}) error {
+	if name == "" || metric == nil {
+		return errors.New("metric name and value must not be empty")
+	}
+	copied := make(Labels, len(labels))
+	for k, v := range labels {
+		copied[k] = v
+	}
+
+	key := name + copied.String()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.entries[key]; ok {
+		return errDuplicateMetric
+	}
+	r.entries[key] = &entry{name: name, labels: copied, metric: metric}
+	return nil
+}
+
+func (r *Registry) Get(name string, labels Labels) interface{} {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if e, ok := r.entries[name+labels.String()]; ok {
+		return e.metric
+	}
+	return nil
+}
+
+func (r *Registry) Unregister(name string, labels Labels) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.entries, name+labels.String())
+}
+
+// Each calls fn for every metric sorted by name and labels, fn must not modify labels.
+func (r *Registry) Each(fn func(name string, labels Labels, metric interface{})) {
+	r.mu.RLock()
+	keys := make([]string, 0, len(r.entries))
+	for key := range r.entries {
+		keys = append(keys, key)
+	}
+	entries := make([]*entry, 0, len(keys))
+	sort.Strings(keys)
+	for _, key := range keys {
+		entries = append(entries, r.entries[key])
+	}
+	r.mu.RUnlock()
+
+	for _, e := range entries {
+		fn(e.name, e.labels, e.metric)
+	}
+}
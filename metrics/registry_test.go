@@ -0,0 +1,33 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLabelsString(t *testing.T) {
+	assert.Equal(t, "", Labels(nil).String())
+	assert.Equal(t, `{code="200",path="/"}`, Labels{"path": "/", "code": "200"}.String())
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	g := NewGauge()
+	assert.Nil(t, r.Register("b_gauge", g))
+	assert.NotNil(t, r.Register("b_gauge", NewGauge()))
+	assert.NotNil(t, r.Register("", NewGauge()))
+	assert.Nil(t, r.RegisterWithLabels("a_counter", Labels{"path": "/"}, NewCounter()))
+	assert.Nil(t, r.RegisterWithLabels("a_counter", Labels{"path": "/login"}, NewCounter()))
+	assert.Equal(t, g, r.Get("b_gauge", nil))
+	assert.Nil(t, r.Get("a_counter", nil))
+
+	var names []string
+	r.Each(func(name string, labels Labels, metric interface{}) {
+		names = append(names, name+labels.String())
+	})
+	assert.Equal(t, []string{`a_counter{path="/"}`, `a_counter{path="/login"}`, "b_gauge"}, names)
+
+	r.Unregister("b_gauge", nil)
+	assert.Nil(t, r.Get("b_gauge", nil))
+}
@@ -0,0 +1,120 @@
+package remotewrite
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// The messages below mirror prometheus/prompb, only the fields remote write needs:
+//
+//	message WriteRequest { repeated TimeSeries timeseries = 1; }
+//	message TimeSeries   { repeated Label labels = 1; repeated Sample samples = 2; }
+//	message Label        { string name = 1; string value = 2; }
+//	message Sample       { double value = 1; int64 timestamp = 2; }
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+type label struct {
+	name, value string
+}
+
+type sample struct {
+	value     float64
+	timestamp int64 // milliseconds since the Unix epoch
+}
+
+type timeSeries struct {
+	labels  []label // sorted by name
+	samples []sample
+}
+
+func (l *label) size() int {
+	return bytesFieldSize(1, len(l.name)) + bytesFieldSize(2, len(l.value))
+}
+
+func (s *sample) size() int {
+	return 1 + 8 + 1 + varintSize(uint64(s.timestamp))
+}
+
+func (ts *timeSeries) size() int {
+	n := 0
+	for i := range ts.labels {
+		n += bytesFieldSize(1, ts.labels[i].size())
+	}
+	for i := range ts.samples {
+		n += bytesFieldSize(2, ts.samples[i].size())
+	}
+	return n
+}
+
+// marshalWriteRequest encodes series as a WriteRequest.
+func marshalWriteRequest(series []timeSeries) []byte {
+	n := 0
+	for i := range series {
+		n += bytesFieldSize(1, series[i].size())
+	}
+	b := make([]byte, 0, n)
+	for i := range series {
+		ts := &series[i]
+		b = appendTag(b, 1, wireBytes)
+		b = appendUvarint(b, uint64(ts.size()))
+		for j := range ts.labels {
+			l := &ts.labels[j]
+			b = appendTag(b, 1, wireBytes)
+			b = appendUvarint(b, uint64(l.size()))
+			b = appendString(b, 1, l.name)
+			b = appendString(b, 2, l.value)
+		}
+		for j := range ts.samples {
+			s := &ts.samples[j]
+			b = appendTag(b, 2, wireBytes)
+			b = appendUvarint(b, uint64(s.size()))
+			b = appendTag(b, 1, wireFixed64)
+			b = appendFixed64(b, math.Float64bits(s.value))
+			b = appendTag(b, 2, wireVarint)
+			b = appendUvarint(b, uint64(s.timestamp))
+		}
+	}
+	return b
+}
+
+func appendTag(b []byte, field int, wire int) []byte {
+	return appendUvarint(b, uint64(field<<3|wire))
+}
+
+func appendString(b []byte, field int, s string) []byte {
+	b = appendTag(b, field, wireBytes)
+	b = appendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+func appendFixed64(b []byte, v uint64) []byte {
+	var tmp [8]byte
+	binary.LittleEndian.PutUint64(tmp[:], v)
+	return append(b, tmp[:]...)
+}
+
+func bytesFieldSize(field int, n int) int {
+	return varintSize(uint64(field<<3)) + varintSize(uint64(n)) + n
+}
+
+func varintSize(v uint64) int {
+	n := 1
+	for v >= 0x80 {
+		v >>= 7
+		n++
+	}
+	return n
+}
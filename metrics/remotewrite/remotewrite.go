@@ -0,0 +1,270 @@
+package remotewrite
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/zjbztianya/go-misc/backoff"
+	"github.com/zjbztianya/go-misc/metrics"
+	"github.com/zjbztianya/go-misc/metrics/internal/expfmt"
+)
+
+const (
+	DefaultPushInterval      = 15 * time.Second
+	DefaultTimeout           = 10 * time.Second
+	DefaultMaxSamplesPerSend = 500
+	DefaultMaxRetries        = 5
+)
+
+var defaultBackoff = backoff.Config{
+	BaseDelay:  100 * time.Millisecond,
+	MaxDelay:   5 * time.Second,
+	Multiplier: 2,
+	Jitter:     0.2,
+}
+
+type Config struct {
+	URL               string         // remote write receiver, such as http://localhost:9090/api/v1/write
+	ExternalLabels    metrics.Labels // added to every series, such as {"job": "batch"}
+	PushInterval      time.Duration
+	Timeout           time.Duration // for a single request
+	MaxSamplesPerSend int
+	MaxRetries        int // retries of a failed request, DefaultMaxRetries if 0, none if negative
+	Backoff           *backoff.Config
+	Client            *http.Client
+	OnError           func(err error) // called with the errors of the background pushes
+}
+
+// Exporter converts the metrics of a registry into Prometheus remote write requests.
+// Gauges, counters and meters become one series each, meters report their count.
+// Sliding counters, sliding windows and histograms become <name>_sum and <name>_count
+// series, histograms additionally get cumulative <name>_bucket{le="..."} series and
+// timers <name>{quantile="..."} series in seconds.
+type Exporter struct {
+	cfg     Config
+	reg     *metrics.Registry
+	backoff *backoff.Exponential
+
+	mu sync.Mutex // serializes pushes
+
+	ctx       context.Context // canceled by Close to abort background retries
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+	closeErr  error
+}
+
+func NewExporter(reg *metrics.Registry, c *Config) (*Exporter, error) {
+	if c.URL == "" {
+		return nil, errors.New("remote write url must not be empty")
+	}
+	cfg := *c
+	if cfg.PushInterval <= 0 {
+		cfg.PushInterval = DefaultPushInterval
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.MaxSamplesPerSend <= 0 {
+		cfg.MaxSamplesPerSend = DefaultMaxSamplesPerSend
+	}
+	if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
+	} else if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = DefaultMaxRetries
+	}
+	if cfg.Backoff == nil {
+		bc := defaultBackoff
+		cfg.Backoff = &bc
+	}
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Exporter{
+		cfg:     cfg,
+		reg:     reg,
+		backoff: backoff.NewExponential(cfg.Backoff),
+		ctx:     ctx,
+		cancel:  cancel,
+	}, nil
+}
+
+// Start pushes every PushInterval in the background until Close is called.
+func (e *Exporter) Start() {
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		ticker := time.NewTicker(e.cfg.PushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				// pushes aborted by Close are not failures
+				if err := e.Push(e.ctx); err != nil && e.ctx.Err() == nil && e.cfg.OnError != nil {
+					e.cfg.OnError(err)
+				}
+			case <-e.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Close stops the background pushing and pushes one last time, which is what
+// short-lived jobs should call before exiting. Later calls return the error of the
+// first one without pushing again.
+func (e *Exporter) Close() error {
+	e.closeOnce.Do(func() {
+		e.cancel()
+		e.wg.Wait()
+		e.closeErr = e.Push(context.Background())
+	})
+	return e.closeErr
+}
+
+// Push sends the current value of every registered metric, in batches of at most
+// MaxSamplesPerSend samples. Every series holds a single sample.
+func (e *Exporter) Push(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	series := e.collect(time.Now())
+	for len(series) > 0 {
+		n := e.cfg.MaxSamplesPerSend
+		if n > len(series) {
+			n = len(series)
+		}
+		if err := e.send(ctx, marshalWriteRequest(series[:n])); err != nil {
+			return err
+		}
+		series = series[n:]
+	}
+	return nil
+}
+
+// collect converts the registry into series holding one sample each.
+func (e *Exporter) collect(now time.Time) []timeSeries {
+	ts := now.UnixNano() / int64(time.Millisecond)
+	var series []timeSeries
+	add := func(name string, labels metrics.Labels, extra *label, v float64) {
+		series = append(series, timeSeries{
+			labels:  e.labels(name, labels, extra),
+			samples: []sample{{value: v, timestamp: ts}},
+		})
+	}
+
+	e.reg.Each(func(name string, labels metrics.Labels, metric interface{}) {
+		switch m := metric.(type) {
+		case metrics.Gauge:
+			add(name, labels, nil, m.Value())
+		case metrics.SlidingCounter:
+			add(name+"_sum", labels, nil, m.Sum())
+			add(name+"_count", labels, nil, m.Count())
+		case metrics.ValueCounter:
+			add(name, labels, nil, m.Value())
+		case metrics.Meter:
+			add(name, labels, nil, float64(m.Count()))
+		case *metrics.SlidingWindow:
+			// windows of custom aggregators have no sum and count to export
+			if total, ok := m.Aggregate().(*metrics.Bucket); ok {
+				add(name+"_sum", labels, nil, total.Sum)
+				add(name+"_count", labels, nil, float64(total.Count))
+			}
+		case metrics.Timer:
+			s := m.Snapshot()
+			for _, q := range expfmt.SummaryQuantiles {
+				add(name, labels, &label{name: "quantile", value: expfmt.FormatFloat(q)}, s.Percentile(q).Seconds())
+			}
+			add(name+"_sum", labels, nil, s.Sum.Seconds())
+			add(name+"_count", labels, nil, float64(s.Count))
+		case metrics.Histogram:
+			var cum int64
+			var sum float64
+			for _, b := range m.Buckets() {
+				cum += b.Count
+				sum += b.Sum
+				add(name+"_bucket", labels, &label{name: "le", value: expfmt.FormatFloat(b.UpperBound)}, float64(cum))
+			}
+			add(name+"_sum", labels, nil, sum)
+			add(name+"_count", labels, nil, float64(cum))
+		}
+	})
+	return series
+}
+
+// labels merges the metric name, external labels, metric labels and extra, sorted by name.
+func (e *Exporter) labels(name string, labels metrics.Labels, extra *label) []label {
+	ls := make([]label, 0, 2+len(labels)+len(e.cfg.ExternalLabels))
+	ls = append(ls, label{name: "__name__", value: expfmt.SanitizeName(name, true)})
+	for k, v := range e.cfg.ExternalLabels {
+		if _, ok := labels[k]; !ok {
+			ls = append(ls, label{name: expfmt.SanitizeName(k, false), value: v})
+		}
+	}
+	for k, v := range labels {
+		ls = append(ls, label{name: expfmt.SanitizeName(k, false), value: v})
+	}
+	if extra != nil {
+		ls = append(ls, *extra)
+	}
+	sort.Slice(ls, func(i, j int) bool {
+		return ls[i].name < ls[j].name
+	})
+	return ls
+}
+
+// send posts one compressed request, retrying on network errors, 5xx and 429 responses.
+func (e *Exporter) send(ctx context.Context, req []byte) error {
+	body := snappy.Encode(nil, req)
+	var err error
+	for retries := 0; ; retries++ {
+		var retry bool
+		if retry, err = e.post(ctx, body); err == nil || !retry || retries >= e.cfg.MaxRetries {
+			return err
+		}
+
+		timer := time.NewTimer(e.backoff.Backoff(retries))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (e *Exporter) post(ctx context.Context, body []byte) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, e.cfg.Timeout)
+	defer cancel()
+	req, err := http.NewRequest(http.MethodPost, e.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	resp, err := e.cfg.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		io.Copy(ioutil.Discard, resp.Body)
+		return false, nil
+	}
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 256))
+	err = fmt.Errorf("remote write returned HTTP status %s: %s", resp.Status, bytes.TrimSpace(msg))
+	return resp.StatusCode/100 == 5 || resp.StatusCode == http.StatusTooManyRequests, err
+}
@@ -0,0 +1,262 @@
+package remotewrite
+
+import (
+	"context"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/zjbztianya/go-misc/backoff"
+	"github.com/zjbztianya/go-misc/metrics"
+	"github.com/zjbztianya/go-misc/metrics/internal/metricstest"
+)
+
+// receiver is a remote write endpoint recording the series it receives.
+type receiver struct {
+	mu       sync.Mutex
+	requests int
+	failures int // number of requests to answer with 503
+	series   map[string]float64
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests++
+	if r.failures > 0 {
+		r.failures--
+		http.Error(w, "try again", http.StatusServiceUnavailable)
+		return
+	}
+	if req.Header.Get("Content-Encoding") != "snappy" {
+		http.Error(w, "not snappy", http.StatusBadRequest)
+		return
+	}
+	compressed, _ := ioutil.ReadAll(req.Body)
+	body, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for _, ts := range fields(body, 1) {
+		var labels []string
+		var value float64
+		for _, l := range fields(ts, 1) {
+			kv := fields(l, 1, 2)
+			labels = append(labels, string(kv[0])+"="+string(kv[1]))
+		}
+		for _, s := range fields(ts, 2) {
+			value = math.Float64frombits(binary.LittleEndian.Uint64(s[1:9]))
+		}
+		r.series[strings.Join(labels, ",")] = value
+	}
+}
+
+// fields returns the length-delimited fields of a protobuf message with the given numbers.
+func fields(msg []byte, numbers ...int) [][]byte {
+	var out [][]byte
+	for len(msg) > 0 {
+		tag, n := binary.Uvarint(msg)
+		msg = msg[n:]
+		switch tag & 7 {
+		case wireVarint:
+			_, n = binary.Uvarint(msg)
+			msg = msg[n:]
+		case wireFixed64:
+			msg = msg[8:]
+		case wireBytes:
+			l, n := binary.Uvarint(msg)
+			value := msg[n : n+int(l)]
+			msg = msg[n+int(l):]
+			for _, num := range numbers {
+				if int(tag>>3) == num {
+					out = append(out, value)
+				}
+			}
+		}
+	}
+	return out
+}
+
+func newReceiver() (*receiver, *httptest.Server) {
+	r := &receiver{series: make(map[string]float64)}
+	return r, httptest.NewServer(r)
+}
+
+func TestExporterPush(t *testing.T) {
+	r, srv := newReceiver()
+	defer srv.Close()
+
+	reg := metrics.NewRegistry()
+	g := metrics.NewGauge()
+	g.Set(42)
+	c := metrics.NewCounter()
+	c.Add(3)
+	sc := metrics.NewSlidingCounter(10, time.Second)
+	sc.Add(2)
+	sc.Add(5)
+	h := metrics.NewHistogram(1)
+	h.Observe(0.5)
+	h.Observe(3)
+	reg.Register("temperature", g)
+	reg.RegisterWithLabels("requests_total", metrics.Labels{"path": "/"}, c)
+	reg.Register("window.latency", sc)
+	reg.Register("size", h)
+	m := metrics.NewMeter()
+	m.Mark(9)
+	reg.Register("events_total", m)
+	tm := metrics.NewTimer(10, time.Second, 1, 2)
+	tm.UpdateDuration(1500 * time.Millisecond)
+	reg.Register("call_seconds", tm)
+	// skipped, a custom aggregator has no sum and count
+	reg.Register("last", metricstest.NewLastWindow())
+
+	e, err := NewExporter(reg, &Config{URL: srv.URL, ExternalLabels: metrics.Labels{"job": "batch"}})
+	assert.Nil(t, err)
+	assert.Nil(t, e.Push(context.Background()))
+	assert.Equal(t, map[string]float64{
+		"__name__=temperature,job=batch":                42,
+		"__name__=events_total,job=batch":               9,
+		"__name__=call_seconds,job=batch,quantile=0.5":  1.5,
+		"__name__=call_seconds,job=batch,quantile=0.9":  1.9,
+		"__name__=call_seconds,job=batch,quantile=0.99": 1.99,
+		"__name__=call_seconds_sum,job=batch":           1.5,
+		"__name__=call_seconds_count,job=batch":         1,
+		"__name__=requests_total,job=batch,path=/":      3,
+		"__name__=window_latency_sum,job=batch":         7,
+		"__name__=window_latency_count,job=batch":       2,
+		"__name__=size_bucket,job=batch,le=1":           1,
+		"__name__=size_bucket,job=batch,le=+Inf":        2,
+		"__name__=size_sum,job=batch":                   3.5,
+		"__name__=size_count,job=batch":                 2,
+	}, r.series)
+	assert.Equal(t, 1, r.requests)
+}
+
+func TestExporterBatching(t *testing.T) {
+	r, srv := newReceiver()
+	defer srv.Close()
+
+	reg := metrics.NewRegistry()
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		reg.Register(name, metrics.NewGauge())
+	}
+	e, err := NewExporter(reg, &Config{URL: srv.URL, MaxSamplesPerSend: 2})
+	assert.Nil(t, err)
+	assert.Nil(t, e.Push(context.Background()))
+	assert.Equal(t, 3, r.requests)
+	assert.Len(t, r.series, 5)
+}
+
+func TestExporterRetry(t *testing.T) {
+	r, srv := newReceiver()
+	defer srv.Close()
+
+	reg := metrics.NewRegistry()
+	reg.Register("gauge", metrics.NewGauge())
+	cfg := &Config{
+		URL:        srv.URL,
+		MaxRetries: 2,
+		Backoff:    &backoff.Config{BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond, Multiplier: 2},
+	}
+	e, err := NewExporter(reg, cfg)
+	assert.Nil(t, err)
+
+	r.failures = 2
+	assert.Nil(t, e.Push(context.Background()))
+	assert.Equal(t, 3, r.requests)
+	assert.Len(t, r.series, 1)
+
+	r.failures = 3
+	assert.NotNil(t, e.Push(context.Background()))
+	assert.Equal(t, 6, r.requests)
+}
+
+func TestExporterNoRetryOnClientError(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		http.Error(w, "bad request", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	reg := metrics.NewRegistry()
+	reg.Register("gauge", metrics.NewGauge())
+	e, err := NewExporter(reg, &Config{URL: srv.URL})
+	assert.Nil(t, err)
+	err = e.Push(context.Background())
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "bad request")
+	assert.Equal(t, 1, requests)
+}
+
+func TestExporterOnError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	reg := metrics.NewRegistry()
+	reg.Register("gauge", metrics.NewGauge())
+	errs := make(chan error, 1)
+	e, err := NewExporter(reg, &Config{
+		URL:          srv.URL,
+		PushInterval: 10 * time.Millisecond,
+		MaxRetries:   -1,
+		OnError: func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		},
+	})
+	assert.Nil(t, err)
+	e.Start()
+	defer e.Close()
+	select {
+	case err := <-errs:
+		assert.Contains(t, err.Error(), "unavailable")
+	case <-time.After(time.Second):
+		t.Fatal("background push error not reported")
+	}
+}
+
+func TestExporterClose(t *testing.T) {
+	r, srv := newReceiver()
+	defer srv.Close()
+
+	reg := metrics.NewRegistry()
+	reg.Register("gauge", metrics.NewGauge())
+	e, err := NewExporter(reg, &Config{URL: srv.URL, PushInterval: time.Hour})
+	assert.Nil(t, err)
+	e.Start()
+	assert.Nil(t, e.Close())
+	assert.Nil(t, e.Close())
+	assert.Equal(t, 1, r.requests)
+}
+
+func TestLabelsSorted(t *testing.T) {
+	e, err := NewExporter(metrics.NewRegistry(), &Config{URL: "http://localhost", ExternalLabels: metrics.Labels{"z": "1", "Env": "x"}})
+	assert.Nil(t, err)
+	ls := e.labels("1st-metric", metrics.Labels{"a.b": "v", "z": "2"}, nil)
+	names := make([]string, len(ls))
+	for i, l := range ls {
+		names[i] = l.name + "=" + l.value
+	}
+	assert.True(t, sort.StringsAreSorted(names))
+	assert.Equal(t, []string{"Env=x", "__name__=_st_metric", "a_b=v", "z=2"}, names)
+}
+
+func TestNewExporter(t *testing.T) {
+	_, err := NewExporter(metrics.NewRegistry(), &Config{})
+	assert.NotNil(t, err)
+}
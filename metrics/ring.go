@@ -0,0 +1,62 @@
+package metrics
+
+import "time"
+
+// Ring is the bucket rotation of a SlidingWindow, shared with other windowed types
+// that keep buckets of their own: size buckets of interval each in a circular array,
+// the bucket at the returned index receiving the current samples. It is not safe for
+// concurrent use, callers guard it with the lock of their buckets.
+type Ring struct {
+	size     int
+	interval time.Duration
+	lastTime time.Time
+	offset   int
+}
+
+func NewRing(size int, interval time.Duration) *Ring {
+	if size <= 0 {
+		panic("rolling window size must greater than 0")
+	}
+	return &Ring{size: size, interval: interval, lastTime: time.Now()}
+}
+
+func (r *Ring) Size() int {
+	return r.size
+}
+
+func (r *Ring) Interval() time.Duration {
+	return r.interval
+}
+
+func (r *Ring) timeSpan() int {
+	return int(time.Since(r.lastTime) / r.interval)
+}
+
+// Advance moves the current bucket to now, calls reset with the index of every bucket
+// reused for a newer interval and returns the index of the current bucket.
+func (r *Ring) Advance(reset func(i int)) int {
+	span := r.timeSpan()
+	if span <= 0 {
+		return r.offset
+	}
+	r.lastTime = r.lastTime.Add(time.Duration(span) * r.interval)
+	if span > r.size {
+		span = r.size
+	}
+	for i := 0; i < span; i++ {
+		reset((r.offset + 1 + i) % r.size)
+	}
+	r.offset = (r.offset + span) % r.size
+	return r.offset
+}
+
+// Each calls fn with the index of every bucket still inside the window, oldest first.
+func (r *Ring) Each(fn func(i int)) {
+	r.each(r.timeSpan(), fn)
+}
+
+func (r *Ring) each(span int, fn func(i int)) {
+	for i := 0; i < r.size-span; i++ {
+		fn((r.offset + span + i + 1) % r.size)
+	}
+}
@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRing(t *testing.T) {
+	interval := 50 * time.Millisecond
+	r := NewRing(3, interval)
+	var reset, valid []int
+	each := func() []int {
+		valid = valid[:0]
+		r.Each(func(i int) {
+			valid = append(valid, i)
+		})
+		return valid
+	}
+	assert.Equal(t, 0, r.Advance(func(i int) { reset = append(reset, i) }))
+	assert.Empty(t, reset)
+	assert.Equal(t, []int{1, 2, 0}, each())
+
+	time.Sleep(interval + interval/2)
+	assert.Equal(t, []int{2, 0}, each())
+	assert.Equal(t, 1, r.Advance(func(i int) { reset = append(reset, i) }))
+	assert.Equal(t, []int{1}, reset)
+	assert.Equal(t, []int{2, 0, 1}, each())
+
+	// a long pause resets every bucket once
+	reset = reset[:0]
+	time.Sleep(5 * interval)
+	assert.Empty(t, each())
+	r.Advance(func(i int) { reset = append(reset, i) })
+	assert.ElementsMatch(t, []int{0, 1, 2}, reset)
+	assert.Panics(t, func() {
+		NewRing(0, interval)
+	})
+}
@@ -0,0 +1,168 @@
+package metrics
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+// gcPauseBuckets are the upper bounds of the GC pause histogram in seconds.
+var gcPauseBuckets = []float64{1e-5, 5e-5, 1e-4, 5e-4, 1e-3, 5e-3, 1e-2, 5e-2, 0.1, 0.5, 1}
+
+// processStats are the process metrics read from the operating system.
+type processStats struct {
+	openFDs    float64
+	maxFDs     float64
+	rssBytes   float64
+	vsizeBytes float64
+	cpuSeconds float64
+}
+
+// RuntimeCollector samples Go runtime and process metrics into gauges, counters and a GC
+// pause histogram registered in a Registry, using the names of the Prometheus Go client.
+// Process metrics are only available on Linux, they are read from /proc/self.
+type RuntimeCollector struct {
+	goroutines  Gauge
+	threads     Gauge
+	heapAlloc   Gauge
+	heapSys     Gauge
+	heapObjects Gauge
+	nextGC      Gauge
+	gcCount     ValueCounter
+	gcPause     Histogram
+
+	openFDs    Gauge
+	maxFDs     Gauge
+	rss        Gauge
+	vsize      Gauge
+	cpuSeconds ValueCounter
+
+	mu        sync.Mutex // serializes collections
+	lastNumGC uint32
+	lastCPU   float64
+	stats     runtime.MemStats
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewRuntimeCollector registers the runtime metrics in r and collects them once.
+func NewRuntimeCollector(r *Registry) (*RuntimeCollector, error) {
+	c := &RuntimeCollector{
+		goroutines:  NewGauge(),
+		threads:     NewGauge(),
+		heapAlloc:   NewGauge(),
+		heapSys:     NewGauge(),
+		heapObjects: NewGauge(),
+		nextGC:      NewGauge(),
+		gcCount:     NewCounter(),
+		gcPause:     NewHistogram(gcPauseBuckets...),
+	}
+	all := map[string]interface{}{
+		"go_goroutines":                c.goroutines,
+		"go_threads":                   c.threads,
+		"go_memstats_heap_alloc_bytes": c.heapAlloc,
+		"go_memstats_heap_sys_bytes":   c.heapSys,
+		"go_memstats_heap_objects":     c.heapObjects,
+		"go_memstats_next_gc_bytes":    c.nextGC,
+		"go_gc_cycles_total":           c.gcCount,
+		"go_gc_pause_seconds":          c.gcPause,
+	}
+	if _, err := readProcessStats(); err == nil {
+		c.openFDs, c.maxFDs, c.rss, c.vsize, c.cpuSeconds = NewGauge(), NewGauge(), NewGauge(), NewGauge(), NewCounter()
+		all["process_open_fds"] = c.openFDs
+		all["process_max_fds"] = c.maxFDs
+		all["process_resident_memory_bytes"] = c.rss
+		all["process_virtual_memory_bytes"] = c.vsize
+		all["process_cpu_seconds_total"] = c.cpuSeconds
+	}
+
+	var registered []string
+	for name, m := range all {
+		if err := r.Register(name, m); err != nil {
+			for _, name := range registered {
+				r.Unregister(name, nil)
+			}
+			return nil, err
+		}
+		registered = append(registered, name)
+	}
+	c.Collect()
+	return c, nil
+}
+
+// Collect samples every metric once.
+func (c *RuntimeCollector) Collect() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.goroutines.Set(float64(runtime.NumGoroutine()))
+	threads, _ := runtime.ThreadCreateProfile(nil)
+	c.threads.Set(float64(threads))
+
+	ms := &c.stats
+	runtime.ReadMemStats(ms)
+	c.heapAlloc.Set(float64(ms.HeapAlloc))
+	c.heapSys.Set(float64(ms.HeapSys))
+	c.heapObjects.Set(float64(ms.HeapObjects))
+	c.nextGC.Set(float64(ms.NextGC))
+	c.gcCount.Add(float64(ms.NumGC - c.lastNumGC))
+
+	// PauseNs is a circular buffer of the most recent pauses
+	n := ms.NumGC - c.lastNumGC
+	if n > uint32(len(ms.PauseNs)) {
+		n = uint32(len(ms.PauseNs))
+	}
+	for i := ms.NumGC - n; i < ms.NumGC; i++ {
+		c.gcPause.Observe(float64(ms.PauseNs[i%uint32(len(ms.PauseNs))]) / 1e9)
+	}
+	c.lastNumGC = ms.NumGC
+
+	if c.openFDs == nil {
+		return
+	}
+	if ps, err := readProcessStats(); err == nil {
+		c.openFDs.Set(ps.openFDs)
+		c.maxFDs.Set(ps.maxFDs)
+		c.rss.Set(ps.rssBytes)
+		c.vsize.Set(ps.vsizeBytes)
+		if ps.cpuSeconds > c.lastCPU {
+			c.cpuSeconds.Add(ps.cpuSeconds - c.lastCPU)
+			c.lastCPU = ps.cpuSeconds
+		}
+	}
+}
+
+// Start collects every interval in the background until Stop is called.
+func (c *RuntimeCollector) Start(interval time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stop != nil {
+		return
+	}
+	c.stop = make(chan struct{})
+	c.wg.Add(1)
+	go func(stop chan struct{}) {
+		defer c.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				c.Collect()
+			case <-stop:
+				return
+			}
+		}
+	}(c.stop)
+}
+
+func (c *RuntimeCollector) Stop() {
+	c.mu.Lock()
+	if c.stop != nil {
+		close(c.stop)
+		c.stop = nil
+	}
+	c.mu.Unlock()
+	c.wg.Wait()
+}
@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRuntimeCollector(t *testing.T) {
+	r := NewRegistry()
+	c, err := NewRuntimeCollector(r)
+	assert.Nil(t, err)
+	_, err = NewRuntimeCollector(r)
+	assert.NotNil(t, err)
+
+	runtime.GC()
+	c.Collect()
+	assert.Greater(t, r.Get("go_goroutines", nil).(Gauge).Value(), 0.0)
+	assert.Greater(t, r.Get("go_memstats_heap_alloc_bytes", nil).(Gauge).Value(), 0.0)
+	assert.Greater(t, r.Get("go_gc_pause_seconds", nil).(Histogram).Count(), int64(0))
+	if runtime.GOOS == "linux" {
+		assert.Greater(t, r.Get("process_open_fds", nil).(Gauge).Value(), 0.0)
+		assert.Greater(t, r.Get("process_max_fds", nil).(Gauge).Value(), 0.0)
+		assert.Greater(t, r.Get("process_resident_memory_bytes", nil).(Gauge).Value(), 0.0)
+		assert.GreaterOrEqual(t, r.Get("process_cpu_seconds_total", nil).(ValueCounter).Value(), 0.0)
+	}
+
+	gcs := r.Get("go_gc_cycles_total", nil).(ValueCounter).Value()
+	c.Start(10 * time.Millisecond)
+	runtime.GC()
+	time.Sleep(50 * time.Millisecond)
+	c.Stop()
+	assert.Greater(t, r.Get("go_gc_cycles_total", nil).(ValueCounter).Value(), gcs)
+}
@@ -0,0 +1,235 @@
+package metrics
+
+import (
+	"container/heap"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+var _ Sample = (*expDecaySample)(nil)
+
+const (
+	// DefaultSampleSize and DefaultSampleAlpha make an exponentially decaying sample that
+	// represents roughly the last 5 minutes with 99.9% confidence and 5% margin of error.
+	DefaultSampleSize  = 1028
+	DefaultSampleAlpha = 0.015
+
+	// rescaleThreshold is how often the landmark moves forward, so weights don't overflow.
+	rescaleThreshold = time.Hour
+	// maxExponent bounds alpha times the age since the landmark, so that the weights and
+	// the priorities, up to 2^53 times higher, stay far from overflowing a float64.
+	maxExponent = 600
+)
+
+// Sample is a statistically representative reservoir of the values of a stream.
+type Sample interface {
+	Update(v float64)
+	// Count returns the number of values ever updated, not only the retained ones.
+	Count() int64
+	// Size returns the number of retained values.
+	Size() int
+	Clear()
+	Snapshot() SampleSnapshot
+}
+
+// SampleSnapshot summarizes the retained values of a Sample weighted by their
+// priorities, all fields are 0 when the sample is empty.
+type SampleSnapshot struct {
+	Count  int64
+	Min    float64
+	Max    float64
+	Mean   float64
+	StdDev float64
+
+	values  []float64 // sorted
+	weights []float64 // normalized, sum to 1
+}
+
+// Quantile returns the value below which the weight q (0 <= q <= 1) of the sample falls.
+func (s SampleSnapshot) Quantile(q float64) float64 {
+	if len(s.values) == 0 {
+		return 0
+	}
+	var cum float64
+	for i, w := range s.weights {
+		cum += w
+		if cum > q {
+			return s.values[i]
+		}
+	}
+	return s.values[len(s.values)-1]
+}
+
+// Values returns the retained values in increasing order.
+func (s SampleSnapshot) Values() []float64 {
+	values := make([]float64, len(s.values))
+	copy(values, s.values)
+	return values
+}
+
+// expDecaySample is the forward decaying priority reservoir of Cormode et al., "Forward
+// Decay: A Practical Time Decay Model for Streaming Systems". Every value is weighted by
+// exp(alpha * t) where t is its age since a landmark, and the size values with the
+// highest weight/random priority are retained, so recent values are more likely to be.
+type expDecaySample struct {
+	mu          sync.Mutex
+	size        int
+	alpha       float64
+	count       int64
+	landmark    time.Time
+	nextRescale time.Time
+	rescaleTime time.Duration // rescaleThreshold, or less for a large alpha
+	values      sampleHeap
+	rand        *rand.Rand
+	now         func() time.Time
+}
+
+// NewExpDecaySample creates an exponentially decaying sample retaining up to size values,
+// the higher alpha the more it is biased toward recent values.
+func NewExpDecaySample(size int, alpha float64) Sample {
+	return newExpDecaySample(size, alpha, time.Now)
+}
+
+func newExpDecaySample(size int, alpha float64, now func() time.Time) *expDecaySample {
+	if size <= 0 {
+		panic("sample size must greater than 0")
+	}
+	if alpha <= 0 {
+		panic("sample alpha must greater than 0")
+	}
+	s := &expDecaySample{
+		size:        size,
+		alpha:       alpha,
+		rescaleTime: rescaleThreshold,
+		values:      make(sampleHeap, 0, size),
+		rand:        rand.New(rand.NewSource(time.Now().UnixNano())),
+		now:         now,
+	}
+	if secs := maxExponent / alpha; secs < rescaleThreshold.Seconds() {
+		s.rescaleTime = time.Duration(secs * float64(time.Second))
+		if s.rescaleTime < time.Millisecond {
+			panic("sample alpha is too large")
+		}
+	}
+	s.landmark = now()
+	s.nextRescale = s.landmark.Add(s.rescaleTime)
+	return s
+}
+
+func (s *expDecaySample) Update(v float64) {
+	s.update(s.now(), v)
+}
+
+func (s *expDecaySample) update(t time.Time, v float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !t.Before(s.nextRescale) {
+		s.rescale(t)
+	}
+	s.count++
+
+	weight := math.Exp(s.alpha * t.Sub(s.landmark).Seconds())
+	// 1-Float64 is in (0, 1], so the priority is finite
+	item := sampleItem{value: v, weight: weight, priority: weight / (1 - s.rand.Float64())}
+	if len(s.values) < s.size {
+		heap.Push(&s.values, item)
+	} else if item.priority > s.values[0].priority {
+		s.values[0] = item
+		heap.Fix(&s.values, 0)
+	}
+}
+
+// rescale moves the landmark to t, scaling every weight and priority down by the same
+// factor keeps the relative order of the retained values.
+func (s *expDecaySample) rescale(t time.Time) {
+	factor := math.Exp(-s.alpha * t.Sub(s.landmark).Seconds())
+	for i := range s.values {
+		s.values[i].weight *= factor
+		s.values[i].priority *= factor
+	}
+	s.landmark = t
+	s.nextRescale = t.Add(s.rescaleTime)
+}
+
+func (s *expDecaySample) Count() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.count
+}
+
+func (s *expDecaySample) Size() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.values)
+}
+
+func (s *expDecaySample) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.count = 0
+	s.values = s.values[:0]
+	s.landmark = s.now()
+	s.nextRescale = s.landmark.Add(s.rescaleTime)
+}
+
+func (s *expDecaySample) Snapshot() SampleSnapshot {
+	s.mu.Lock()
+	items := make([]sampleItem, len(s.values))
+	copy(items, s.values)
+	snap := SampleSnapshot{Count: s.count}
+	s.mu.Unlock()
+
+	if len(items) == 0 {
+		return snap
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].value < items[j].value
+	})
+	var total float64
+	for _, it := range items {
+		total += it.weight
+	}
+	snap.values = make([]float64, len(items))
+	snap.weights = make([]float64, len(items))
+	for i, it := range items {
+		snap.values[i] = it.value
+		snap.weights[i] = it.weight / total
+		snap.Mean += it.value * snap.weights[i]
+	}
+	var variance float64
+	for i, v := range snap.values {
+		d := v - snap.Mean
+		variance += snap.weights[i] * d * d
+	}
+	snap.StdDev = math.Sqrt(variance)
+	snap.Min = snap.values[0]
+	snap.Max = snap.values[len(items)-1]
+	return snap
+}
+
+type sampleItem struct {
+	value    float64
+	weight   float64
+	priority float64
+}
+
+// sampleHeap is a min heap of priorities, the root is the first value to evict.
+type sampleHeap []sampleItem
+
+func (h sampleHeap) Len() int           { return len(h) }
+func (h sampleHeap) Less(i, j int) bool { return h[i].priority < h[j].priority }
+func (h sampleHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *sampleHeap) Push(x interface{}) {
+	*h = append(*h, x.(sampleItem))
+}
+
+func (h *sampleHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
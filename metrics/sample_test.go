@@ -0,0 +1,149 @@
+package metrics
+
+import (
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewExpDecaySample(t *testing.T) {
+	assert.Panics(t, func() {
+		NewExpDecaySample(0, DefaultSampleAlpha)
+	})
+	assert.Panics(t, func() {
+		NewExpDecaySample(10, 0)
+	})
+
+	s := NewExpDecaySample(DefaultSampleSize, DefaultSampleAlpha).Snapshot()
+	assert.Equal(t, int64(0), s.Count)
+	assert.Equal(t, 0.0, s.Quantile(0.5))
+	assert.Empty(t, s.Values())
+}
+
+func TestExpDecaySampleSnapshot(t *testing.T) {
+	s := NewExpDecaySample(100, DefaultSampleAlpha)
+	for i := 1; i <= 10; i++ {
+		s.Update(float64(i))
+	}
+	assert.Equal(t, int64(10), s.Count())
+	assert.Equal(t, 10, s.Size())
+
+	snap := s.Snapshot()
+	assert.Equal(t, []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, snap.Values())
+	assert.Equal(t, 1.0, snap.Min)
+	assert.Equal(t, 10.0, snap.Max)
+	// values updated at nearly the same time have nearly equal weights
+	assert.InDelta(t, 5.5, snap.Mean, 1e-3)
+	assert.InDelta(t, math.Sqrt(8.25), snap.StdDev, 1e-3)
+	assert.Equal(t, 1.0, snap.Quantile(0))
+	assert.Equal(t, 5.0, snap.Quantile(0.45))
+	assert.Equal(t, 10.0, snap.Quantile(0.99))
+	assert.Equal(t, 10.0, snap.Quantile(1))
+
+	s.Clear()
+	assert.Equal(t, int64(0), s.Count())
+	assert.Equal(t, 0, s.Size())
+}
+
+func TestExpDecaySampleSize(t *testing.T) {
+	s := NewExpDecaySample(100, DefaultSampleAlpha)
+	for i := 0; i < 1000; i++ {
+		s.Update(float64(i))
+	}
+	assert.Equal(t, int64(1000), s.Count())
+	assert.Equal(t, 100, s.Size())
+	for _, v := range s.Snapshot().Values() {
+		assert.True(t, v >= 0 && v < 1000)
+	}
+}
+
+func TestExpDecaySampleRecentBias(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1600000000, 0)}
+	s := newExpDecaySample(100, 0.1, clock.now)
+	for i := 0; i < 1000; i++ {
+		s.Update(1)
+	}
+	// 5 minutes later the weight of new values is e^30 times higher
+	clock.advance(5 * time.Minute)
+	for i := 0; i < 1000; i++ {
+		s.Update(2)
+	}
+	snap := s.Snapshot()
+	assert.Equal(t, 2.0, snap.Min)
+	assert.Equal(t, 2.0, snap.Quantile(0.5))
+}
+
+func TestExpDecaySampleRescale(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1600000000, 0)}
+	s := newExpDecaySample(10, DefaultSampleAlpha, clock.now)
+	s.Update(1)
+	clock.advance(30 * time.Minute)
+	s.Update(2)
+	before := s.Snapshot()
+
+	// moving the landmark keeps the relative weights
+	clock.advance(rescaleThreshold)
+	s.rescale(clock.now())
+	after := s.Snapshot()
+	assert.Equal(t, clock.now(), s.landmark)
+	assert.InDelta(t, before.Mean, after.Mean, 1e-9)
+	assert.InDelta(t, before.StdDev, after.StdDev, 1e-9)
+
+	// without rescaling the weight of this value would overflow
+	clock.advance(24 * time.Hour)
+	s.Update(3)
+	snap := s.Snapshot()
+	assert.False(t, math.IsNaN(snap.Mean))
+	assert.InDelta(t, 3.0, snap.Mean, 1e-9)
+}
+
+func TestExpDecaySampleLargeAlpha(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1600000000, 0)}
+	// e^(0.5*3599) overflows, the landmark must move more often than every hour
+	s := newExpDecaySample(10, 0.5, clock.now)
+	for i := 0; i < 40; i++ {
+		s.Update(float64(i))
+		clock.advance(5 * time.Minute)
+	}
+	for _, item := range s.values {
+		assert.False(t, math.IsInf(item.priority, 0) || math.IsNaN(item.priority))
+	}
+	snap := s.Snapshot()
+	assert.InDelta(t, 39.0, snap.Mean, 1e-9)
+	assert.Equal(t, 39.0, snap.Quantile(0.5))
+
+	assert.Panics(t, func() {
+		newExpDecaySample(10, 1e12, clock.now)
+	})
+}
+
+func TestExpDecaySampleConcurrent(t *testing.T) {
+	s := NewExpDecaySample(50, DefaultSampleAlpha)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				s.Update(float64(i))
+				if i%100 == 0 {
+					s.Snapshot()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(8000), s.Count())
+	assert.Equal(t, 50, s.Size())
+}
+
+func BenchmarkExpDecaySampleUpdate(b *testing.B) {
+	s := NewExpDecaySample(DefaultSampleSize, DefaultSampleAlpha)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Update(float64(i))
+	}
+}
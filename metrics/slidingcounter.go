@@ -7,22 +7,42 @@ import (
 
 var _ SlidingCounter = (*slidingCounter)(nil)
 
+// SlidingCounter is a counter over a sliding time window.
+// All statistics only consider buckets that are still inside the window,
+// and return 0 when the window holds no samples.
 type SlidingCounter interface {
 	Counter
 	Reduce(func(b *Bucket))
+	// Min returns the smallest sample in the window.
 	Min() float64
+	// Max returns the largest sample in the window.
 	Max() float64
+	// Last returns the most recently added sample.
+	Last() float64
+	// Count returns the number of samples in the window.
 	Count() float64
-	Avg() float64
+	// Sum returns the sum of all samples in the window.
 	Sum() float64
+	// Avg returns the mean value per sample.
+	Avg() float64
+	// BucketAvg returns the mean sum per bucket, counting only buckets that received samples.
+	BucketAvg() float64
+	// StdDev returns the population standard deviation of the samples.
+	StdDev() float64
+	// Rate returns the sum per second over the full window duration.
+	Rate() float64
 }
 
 type slidingCounter struct {
 	win *SlidingWindow
 }
 
-func NewSlidingCounter(size int, interval time.Duration) SlidingCounter {
-	return &slidingCounter{win: NewSlidingWindow(size, interval)}
+func NewSlidingCounter(size int, interval time.Duration, options ...WindowOption) SlidingCounter {
+	win := NewSlidingWindow(size, interval, options...)
+	if !win.bucketed() {
+		panic("sliding counter does not support custom aggregators")
+	}
+	return &slidingCounter{win: win}
 }
 
 func (s *slidingCounter) Inc() {
@@ -33,27 +53,57 @@ func (s *slidingCounter) Add(delta float64) {
 	s.win.Add(delta)
 }
 
+// Value returns the sum of the window.
+func (s *slidingCounter) Value() float64 {
+	return s.Sum()
+}
+
 func (s *slidingCounter) Reduce(fn func(b *Bucket)) {
 	s.win.Reduce(fn)
 }
 
-func (s *slidingCounter) Min() float64 {
-	v := math.MaxFloat64
+// reduceNonEmpty is like Reduce but skips buckets without samples.
+func (s *slidingCounter) reduceNonEmpty(fn func(b *Bucket)) {
 	s.Reduce(func(b *Bucket) {
-		if v > b.Sum {
-			v = b.Sum
+		if b.Count > 0 {
+			fn(b)
 		}
 	})
+}
+
+func (s *slidingCounter) Min() float64 {
+	v, found := math.Inf(1), false
+	s.reduceNonEmpty(func(b *Bucket) {
+		found = true
+		if v > b.Min {
+			v = b.Min
+		}
+	})
+	if !found {
+		return 0
+	}
 	return v
 }
 
 func (s *slidingCounter) Max() float64 {
-	v := math.SmallestNonzeroFloat64
-	s.Reduce(func(b *Bucket) {
-		if v < b.Sum {
-			v = b.Sum
+	v, found := math.Inf(-1), false
+	s.reduceNonEmpty(func(b *Bucket) {
+		found = true
+		if v < b.Max {
+			v = b.Max
 		}
 	})
+	if !found {
+		return 0
+	}
+	return v
+}
+
+func (s *slidingCounter) Last() float64 {
+	var v float64
+	s.reduceNonEmpty(func(b *Bucket) {
+		v = b.Last
+	})
 	return v
 }
 
@@ -65,18 +115,61 @@ func (s *slidingCounter) Count() float64 {
 	return float64(v)
 }
 
-func (s *slidingCounter) Avg() float64 {
+func (s *slidingCounter) Sum() float64 {
 	var v float64
 	s.Reduce(func(b *Bucket) {
 		v += b.Sum
 	})
-	return v / float64(s.win.Size())
+	return v
 }
 
-func (s *slidingCounter) Sum() float64 {
-	var v float64
+func (s *slidingCounter) Avg() float64 {
+	var sum float64
+	var count int64
 	s.Reduce(func(b *Bucket) {
-		v += b.Sum
+		sum += b.Sum
+		count += b.Count
 	})
-	return v
+	if count == 0 {
+		return 0
+	}
+	return sum / float64(count)
+}
+
+func (s *slidingCounter) BucketAvg() float64 {
+	var sum float64
+	var buckets int
+	s.reduceNonEmpty(func(b *Bucket) {
+		sum += b.Sum
+		buckets++
+	})
+	if buckets == 0 {
+		return 0
+	}
+	return sum / float64(buckets)
+}
+
+func (s *slidingCounter) StdDev() float64 {
+	var sum, sumSq float64
+	var count int64
+	s.Reduce(func(b *Bucket) {
+		sum += b.Sum
+		sumSq += b.SumSq
+		count += b.Count
+	})
+	if count == 0 {
+		return 0
+	}
+	mean := sum / float64(count)
+	variance := sumSq/float64(count) - mean*mean
+	if variance < 0 {
+		// rounding error
+		return 0
+	}
+	return math.Sqrt(variance)
+}
+
+func (s *slidingCounter) Rate() float64 {
+	span := time.Duration(s.win.Size()) * s.win.ring.interval
+	return s.Sum() / span.Seconds()
 }
@@ -40,3 +40,41 @@ func TestSlidingCounterSum(t *testing.T) {
 	}
 	assert.Equal(t, 3.0, r.Sum())
 }
+
+func TestSlidingCounterEmpty(t *testing.T) {
+	r := NewSlidingCounter(3, time.Second)
+	assert.Equal(t, 0.0, r.Min())
+	assert.Equal(t, 0.0, r.Max())
+	assert.Equal(t, 0.0, r.Last())
+	assert.Equal(t, 0.0, r.Avg())
+	assert.Equal(t, 0.0, r.BucketAvg())
+	assert.Equal(t, 0.0, r.StdDev())
+	assert.Equal(t, 0.0, r.Rate())
+}
+
+func TestSlidingCounterStats(t *testing.T) {
+	size := 4
+	interval := 50 * time.Millisecond
+	r := NewSlidingCounter(size, interval)
+	r.Add(-2)
+	r.Add(-4)
+	time.Sleep(interval)
+	r.Add(4)
+	r.Add(6)
+	assert.Equal(t, -4.0, r.Min())
+	assert.Equal(t, 6.0, r.Max())
+	assert.Equal(t, 6.0, r.Last())
+	assert.Equal(t, 4.0, r.Sum())
+	assert.Equal(t, 1.0, r.Avg())
+	assert.Equal(t, 2.0, r.BucketAvg())
+	assert.InDelta(t, 4.123, r.StdDev(), 0.001)
+	assert.InDelta(t, 20.0, r.Rate(), 1e-9)
+}
+
+func TestSlidingCounterMaxNegative(t *testing.T) {
+	r := NewSlidingCounter(3, time.Second)
+	r.Add(-3)
+	r.Add(-1)
+	assert.Equal(t, -1.0, r.Max())
+	assert.Equal(t, -3.0, r.Min())
+}
@@ -7,32 +7,68 @@ import (
 
 type WindowOption func(*SlidingWindow)
 
+// Aggregator accumulates the samples of one bucket of a SlidingWindow, so the ring
+// buffer and rotation logic can be shared by windows of sums, histograms, sets, etc.
+type Aggregator interface {
+	Add(v float64)
+	Reset()
+	// Merge folds o, which has the same concrete type, into the aggregator.
+	Merge(o Aggregator)
+}
+
+// WithAggregator makes the window keep buckets created by newAggregator instead of *Bucket.
+// Reduce and Snapshot only work with *Bucket, use ReduceAggregators and Aggregate instead.
+// The exporters skip the buckets of such windows.
+func WithAggregator(newAggregator func() Aggregator) WindowOption {
+	return func(w *SlidingWindow) {
+		w.newAggregator = newAggregator
+	}
+}
+
+// WithEpochAlignment aligns bucket boundaries to multiples of the interval since
+// the Unix epoch instead of the construction time, so windows of different
+// processes cover identical time ranges and their snapshots merge exactly.
+func WithEpochAlignment() WindowOption {
+	return func(w *SlidingWindow) {
+		w.ring.lastTime = alignTime(w.ring.lastTime, w.ring.interval)
+	}
+}
+
 type SlidingWindow struct {
-	mu       sync.RWMutex
-	win      *window
-	interval time.Duration
-	lastTime time.Time
+	mu            sync.RWMutex
+	ring          *Ring
+	buckets       []Aggregator
+	newAggregator func() Aggregator
 }
 
 func NewSlidingWindow(size int, interval time.Duration, options ...WindowOption) *SlidingWindow {
-	if size <= 0 {
-		panic("rolling window size must greater than 0")
-	}
 	w := &SlidingWindow{
-		mu:       sync.RWMutex{},
-		win:      newWindow(size),
-		interval: interval,
-		lastTime: time.Now(),
+		mu:   sync.RWMutex{},
+		ring: NewRing(size, interval),
+		newAggregator: func() Aggregator {
+			return new(Bucket)
+		},
 	}
 
 	for _, opt := range options {
 		opt(w)
 	}
+	w.buckets = make([]Aggregator, size)
+	for i := range w.buckets {
+		w.buckets[i] = w.newAggregator()
+	}
 	return w
 }
 
-func (r *SlidingWindow) timeSpan() int {
-	return int(time.Since(r.lastTime) / r.interval)
+// alignTime truncates t to a multiple of interval since the Unix epoch.
+func alignTime(t time.Time, interval time.Duration) time.Time {
+	ns := t.UnixNano()
+	rem := ns % int64(interval)
+	if rem < 0 {
+		rem += int64(interval)
+	}
+	// keep the monotonic clock reading of t
+	return t.Add(-time.Duration(rem))
 }
 
 func (r *SlidingWindow) Inc() {
@@ -42,71 +78,112 @@ func (r *SlidingWindow) Inc() {
 func (r *SlidingWindow) Add(v float64) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	span := r.timeSpan()
-	if span > 0 {
-		r.lastTime = r.lastTime.Add(time.Duration(int(r.interval) * span))
-		r.win.adjustOffset(span)
-	}
-	r.win.add(v)
+	r.buckets[r.ring.Advance(r.reset)].Add(v)
+}
+
+func (r *SlidingWindow) reset(i int) {
+	r.buckets[i].Reset()
 }
 
+// Reduce calls fn for every bucket still inside the window, oldest first.
+// It panics if the window was created with a custom aggregator.
 func (r *SlidingWindow) Reduce(fn func(b *Bucket)) {
+	r.ReduceAggregators(func(a Aggregator) {
+		fn(a.(*Bucket))
+	})
+}
+
+// ReduceAggregators calls fn for every bucket still inside the window, oldest first.
+func (r *SlidingWindow) ReduceAggregators(fn func(a Aggregator)) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
-	span := r.timeSpan()
-	if count := r.win.size - span; count > 0 {
-		for i := 0; i < count; i++ {
-			offset := (r.win.offset + span + i + 1) % r.win.size
-			fn(r.win.buckets[offset])
-		}
-	}
+	r.ring.Each(func(i int) {
+		fn(r.buckets[i])
+	})
 }
 
-func (r *SlidingWindow) Size() int {
-	return r.win.size
+// Aggregate merges every bucket still inside the window into a new aggregator.
+func (r *SlidingWindow) Aggregate() Aggregator {
+	total := r.newAggregator()
+	r.ReduceAggregators(total.Merge)
+	return total
 }
 
-// window is acting as a circular array(ring buffer)
-type window struct {
-	buckets []*Bucket
-	size    int // bucket numbers
-	offset  int
+// Snapshot returns an immutable copy of every bucket in the window, oldest first.
+// Buckets that already slid out of the window are reported empty.
+// It panics if the window was created with a custom aggregator.
+func (r *SlidingWindow) Snapshot() *Snapshot {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	span := r.ring.timeSpan()
+	size, interval := r.ring.size, r.ring.interval
+	buckets := make([]Bucket, 0, size)
+	r.ring.each(span, func(i int) {
+		buckets = append(buckets, *r.buckets[i].(*Bucket))
+	})
+	buckets = buckets[:size] // the newest ones slid out and stay empty
+	current := r.ring.lastTime.Add(time.Duration(span) * interval)
+	start := current.Add(-time.Duration(size-1) * interval)
+	return &Snapshot{start: start.Round(0), interval: interval, buckets: buckets}
 }
 
-func newWindow(size int) *window {
-	w := &window{size: size, buckets: make([]*Bucket, size)}
-	for i := 0; i < size; i++ {
-		w.buckets[i] = new(Bucket)
-	}
-	return w
+// bucketed reports whether the buckets are *Bucket, which Reduce and Snapshot need.
+func (r *SlidingWindow) bucketed() bool {
+	_, ok := r.newAggregator().(*Bucket)
+	return ok
 }
 
-func (w *window) adjustOffset(span int) {
-	if span > w.size {
-		span = w.size
-	}
-
-	for i := 0; i < span; i++ {
-		w.buckets[(w.offset+1+i)%w.size].reset()
-	}
-	w.offset = (w.offset + span) % w.size
+func (r *SlidingWindow) Size() int {
+	return r.ring.size
 }
 
-func (w *window) add(v float64) {
-	w.buckets[w.offset].add(v)
-}
+var _ Aggregator = (*Bucket)(nil)
 
+// Bucket is the default Aggregator of a SlidingWindow.
+// Min, Max and Last are only meaningful when Count is greater than 0.
 type Bucket struct {
-	Sum   float64
-	Count int64
+	Sum   float64 `json:"sum,omitempty"`
+	Count int64   `json:"count,omitempty"`
+	Min   float64 `json:"min,omitempty"`
+	Max   float64 `json:"max,omitempty"`
+	Last  float64 `json:"last,omitempty"`
+	SumSq float64 `json:"sumsq,omitempty"` // sum of squares, used for standard deviation
 }
 
-func (b *Bucket) add(v float64) {
+func (b *Bucket) Add(v float64) {
+	if b.Count == 0 || v < b.Min {
+		b.Min = v
+	}
+	if b.Count == 0 || v > b.Max {
+		b.Max = v
+	}
 	b.Sum += v
+	b.SumSq += v * v
+	b.Last = v
 	b.Count++
 }
 
-func (b *Bucket) reset() {
-	b.Count = 0
-	b.Sum = 0
+func (b *Bucket) Merge(o Aggregator) {
+	b.merge(o.(*Bucket))
+}
+
+// merge folds the samples of o into b, the Last value of o wins when it has samples.
+func (b *Bucket) merge(o *Bucket) {
+	if o.Count == 0 {
+		return
+	}
+	if b.Count == 0 || o.Min < b.Min {
+		b.Min = o.Min
+	}
+	if b.Count == 0 || o.Max > b.Max {
+		b.Max = o.Max
+	}
+	b.Sum += o.Sum
+	b.SumSq += o.SumSq
+	b.Last = o.Last
+	b.Count += o.Count
+}
+
+func (b *Bucket) Reset() {
+	*b = Bucket{}
 }
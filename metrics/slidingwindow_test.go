@@ -1,6 +1,7 @@
 package metrics
 
 import (
+	"math"
 	"testing"
 	"time"
 
@@ -98,3 +99,70 @@ func BenchmarkSlidingWindowInc(b *testing.B) {
 		r.Inc()
 	}
 }
+
+func TestSlidingWindowEpochAlignment(t *testing.T) {
+	interval := 50 * time.Millisecond
+	a := NewSlidingWindow(3, interval, WithEpochAlignment())
+	time.Sleep(interval / 3)
+	b := NewSlidingWindow(3, interval, WithEpochAlignment())
+	a.Add(1)
+	b.Add(2)
+
+	sa, sb := a.Snapshot(), b.Snapshot()
+	assert.Equal(t, int64(0), sa.Start().UnixNano()%int64(interval))
+	m, err := sa.Merge(sb)
+	assert.Nil(t, err)
+	assert.Equal(t, 3.0, m.Total().Sum)
+}
+
+func TestAlignTime(t *testing.T) {
+	assert.Equal(t, int64(1600000020), alignTime(time.Unix(1600000059, 999), time.Minute).Unix())
+	assert.Equal(t, int64(-60), alignTime(time.Unix(-1, 0), time.Minute).Unix())
+}
+
+type maxAggregator struct {
+	max float64
+}
+
+func (m *maxAggregator) Add(v float64) {
+	m.max = math.Max(m.max, v)
+}
+
+func (m *maxAggregator) Reset() {
+	m.max = 0
+}
+
+func (m *maxAggregator) Merge(o Aggregator) {
+	m.Add(o.(*maxAggregator).max)
+}
+
+func TestSlidingWindowAggregator(t *testing.T) {
+	interval := 50 * time.Millisecond
+	r := NewSlidingWindow(2, interval, WithAggregator(func() Aggregator {
+		return new(maxAggregator)
+	}))
+	r.Add(3)
+	r.Add(1)
+	time.Sleep(interval)
+	r.Add(2)
+
+	var maxes []float64
+	r.ReduceAggregators(func(a Aggregator) {
+		maxes = append(maxes, a.(*maxAggregator).max)
+	})
+	assert.Equal(t, []float64{3, 2}, maxes)
+	assert.Equal(t, 3.0, r.Aggregate().(*maxAggregator).max)
+	assert.Panics(t, func() {
+		r.Snapshot()
+	})
+	assert.Panics(t, func() {
+		NewSlidingCounter(2, interval, WithAggregator(func() Aggregator {
+			return new(maxAggregator)
+		}))
+	})
+
+	time.Sleep(interval)
+	assert.Equal(t, 2.0, r.Aggregate().(*maxAggregator).max)
+	time.Sleep(interval)
+	assert.Equal(t, 0.0, r.Aggregate().(*maxAggregator).max)
+}
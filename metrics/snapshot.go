@@ -0,0 +1,274 @@
+package metrics
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math"
+	"time"
+)
+
+const (
+	snapshotVersion = 1
+	// maxMergedBuckets bounds the range of a merged snapshot, which gets a bucket for
+	// every interval between the inputs.
+	maxMergedBuckets = 1 << 20
+)
+
+var (
+	errIntervalMismatch = errors.New("snapshot intervals do not match")
+	errInvalidSnapshot  = errors.New("invalid snapshot encoding")
+	errMergeRange       = errors.New("merged snapshots span too many buckets")
+)
+
+// Snapshot is an immutable copy of the buckets of a SlidingWindow.
+// Bucket i covers [Start()+i*Interval(), Start()+(i+1)*Interval()).
+type Snapshot struct {
+	start    time.Time
+	interval time.Duration
+	buckets  []Bucket
+}
+
+// NewSnapshot builds a snapshot from buckets ordered oldest first.
+func NewSnapshot(start time.Time, interval time.Duration, buckets []Bucket) *Snapshot {
+	if interval <= 0 {
+		panic("snapshot interval must greater than 0")
+	}
+	s := &Snapshot{start: start.Round(0), interval: interval, buckets: make([]Bucket, len(buckets))}
+	copy(s.buckets, buckets)
+	return s
+}
+
+func (s *Snapshot) Start() time.Time {
+	return s.start
+}
+
+func (s *Snapshot) End() time.Time {
+	return s.BucketStart(len(s.buckets))
+}
+
+func (s *Snapshot) Interval() time.Duration {
+	return s.interval
+}
+
+func (s *Snapshot) Len() int {
+	return len(s.buckets)
+}
+
+func (s *Snapshot) Bucket(i int) Bucket {
+	return s.buckets[i]
+}
+
+func (s *Snapshot) BucketStart(i int) time.Time {
+	return s.start.Add(time.Duration(i) * s.interval)
+}
+
+// Buckets returns a copy of the buckets, oldest first.
+func (s *Snapshot) Buckets() []Bucket {
+	buckets := make([]Bucket, len(s.buckets))
+	copy(buckets, s.buckets)
+	return buckets
+}
+
+// Total merges all buckets into one.
+func (s *Snapshot) Total() Bucket {
+	var total Bucket
+	for i := range s.buckets {
+		total.merge(&s.buckets[i])
+	}
+	return total
+}
+
+// Merge returns a new snapshot combining s with others. Buckets of others are
+// aligned to the bucket grid of s: each one lands in the bucket of s whose start
+// is nearest to its own, so windows created at slightly different moments in
+// different processes still add up. The result covers the union of all ranges,
+// an error is returned when it spans more than 1<<20 buckets.
+func (s *Snapshot) Merge(others ...*Snapshot) (*Snapshot, error) {
+	lo, hi := 0, len(s.buckets)
+	for _, o := range others {
+		if o.interval != s.interval {
+			return nil, errIntervalMismatch
+		}
+		if len(o.buckets) == 0 {
+			continue
+		}
+		first := s.align(o.start)
+		if first < -maxMergedBuckets || first > maxMergedBuckets {
+			return nil, errMergeRange
+		}
+		if first < lo {
+			lo = first
+		}
+		if last := first + len(o.buckets); last > hi {
+			hi = last
+		}
+	}
+	if hi-lo > maxMergedBuckets {
+		return nil, errMergeRange
+	}
+
+	merged := &Snapshot{
+		start:    s.BucketStart(lo),
+		interval: s.interval,
+		buckets:  make([]Bucket, hi-lo),
+	}
+	copy(merged.buckets[-lo:], s.buckets)
+	for _, o := range others {
+		first := s.align(o.start) - lo
+		for i := range o.buckets {
+			merged.buckets[first+i].merge(&o.buckets[i])
+		}
+	}
+	return merged, nil
+}
+
+// align returns the index of the bucket of s whose start is nearest to t.
+func (s *Snapshot) align(t time.Time) int {
+	d := t.Sub(s.start)
+	half := s.interval / 2
+	if d >= 0 {
+		return int((d + half) / s.interval)
+	}
+	return -int((-d + half - 1) / s.interval)
+}
+
+// MarshalBinary encodes the snapshot compactly: empty buckets take one byte.
+func (s *Snapshot) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, 0, 1+3*binary.MaxVarintLen64+len(s.buckets))
+	buf = append(buf, snapshotVersion)
+	buf = appendVarint(buf, s.start.UnixNano())
+	buf = appendVarint(buf, int64(s.interval))
+	buf = appendUvarint(buf, uint64(len(s.buckets)))
+	for _, b := range s.buckets {
+		buf = appendUvarint(buf, uint64(b.Count))
+		if b.Count == 0 {
+			continue
+		}
+		for _, v := range [...]float64{b.Sum, b.SumSq, b.Min, b.Max, b.Last} {
+			buf = appendFloat64(buf, v)
+		}
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary decodes a snapshot encoded by MarshalBinary.
+func (s *Snapshot) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 || data[0] != snapshotVersion {
+		return errInvalidSnapshot
+	}
+	d := decoder{buf: data[1:]}
+	start := d.varint()
+	interval := d.varint()
+	n := d.uvarint()
+	if d.err != nil || interval <= 0 || n > uint64(len(d.buf)) {
+		return errInvalidSnapshot
+	}
+
+	buckets := make([]Bucket, n)
+	for i := range buckets {
+		b := &buckets[i]
+		count := d.uvarint()
+		if count > math.MaxInt64 {
+			return errInvalidSnapshot
+		}
+		b.Count = int64(count)
+		if b.Count == 0 {
+			continue
+		}
+		b.Sum, b.SumSq, b.Min, b.Max, b.Last = d.float64(), d.float64(), d.float64(), d.float64(), d.float64()
+	}
+	if d.err != nil || len(d.buf) != 0 {
+		return errInvalidSnapshot
+	}
+
+	s.start = time.Unix(0, start)
+	s.interval = time.Duration(interval)
+	s.buckets = buckets
+	return nil
+}
+
+type snapshotJSON struct {
+	Start    time.Time     `json:"start"`
+	Interval time.Duration `json:"interval"`
+	Buckets  []Bucket      `json:"buckets"`
+}
+
+func (s *Snapshot) MarshalJSON() ([]byte, error) {
+	return json.Marshal(snapshotJSON{Start: s.start, Interval: s.interval, Buckets: s.buckets})
+}
+
+func (s *Snapshot) UnmarshalJSON(data []byte) error {
+	var v snapshotJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if v.Interval <= 0 {
+		return errInvalidSnapshot
+	}
+	s.start = v.Start
+	s.interval = v.Interval
+	s.buckets = v.Buckets
+	return nil
+}
+
+func appendVarint(buf []byte, v int64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	return append(buf, tmp[:binary.PutVarint(tmp[:], v)]...)
+}
+
+func appendUvarint(buf []byte, v uint64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	return append(buf, tmp[:binary.PutUvarint(tmp[:], v)]...)
+}
+
+func appendFloat64(buf []byte, v float64) []byte {
+	var tmp [8]byte
+	binary.LittleEndian.PutUint64(tmp[:], math.Float64bits(v))
+	return append(buf, tmp[:]...)
+}
+
+// decoder reads the fields written by the append helpers, remembering the first error.
+type decoder struct {
+	buf []byte
+	err error
+}
+
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(d.buf)
+	if n <= 0 {
+		d.err = errInvalidSnapshot
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.buf)
+	if n <= 0 {
+		d.err = errInvalidSnapshot
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+func (d *decoder) float64() float64 {
+	if d.err != nil {
+		return 0
+	}
+	if len(d.buf) < 8 {
+		d.err = errInvalidSnapshot
+		return 0
+	}
+	v := math.Float64frombits(binary.LittleEndian.Uint64(d.buf))
+	d.buf = d.buf[8:]
+	return v
+}
@@ -0,0 +1,119 @@
+package metrics
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlidingWindowSnapshot(t *testing.T) {
+	size := 3
+	interval := 50 * time.Millisecond
+	r := NewSlidingWindow(size, interval)
+	r.Add(1)
+	time.Sleep(interval)
+	r.Add(2)
+	r.Add(3)
+
+	s := r.Snapshot()
+	assert.Equal(t, size, s.Len())
+	assert.Equal(t, interval, s.Interval())
+	sums := make([]float64, 0, s.Len())
+	for _, b := range s.Buckets() {
+		sums = append(sums, b.Sum)
+	}
+	assert.Equal(t, []float64{0, 1, 5}, sums)
+	assert.Equal(t, s.Start().Add(3*interval), s.End())
+
+	// the snapshot must not change when the window does
+	r.Add(4)
+	assert.Equal(t, 9.0, r.Snapshot().Bucket(2).Sum)
+	assert.Equal(t, 5.0, s.Bucket(2).Sum)
+
+	// buckets without new samples since the last rotation are the newest ones
+	time.Sleep(interval)
+	s = r.Snapshot()
+	sums = sums[:0]
+	for _, b := range s.Buckets() {
+		sums = append(sums, b.Sum)
+	}
+	assert.Equal(t, []float64{1, 9, 0}, sums)
+}
+
+func TestSnapshotMerge(t *testing.T) {
+	start := time.Unix(1600000000, 0)
+	interval := time.Second
+	a := NewSnapshot(start, interval, []Bucket{
+		{Sum: 1, Count: 1, Min: 1, Max: 1, Last: 1, SumSq: 1},
+		{Sum: 2, Count: 1, Min: 2, Max: 2, Last: 2, SumSq: 4},
+	})
+	// created 1.3s later in another process, its first bucket lines up with the second of a
+	b := NewSnapshot(start.Add(1300*time.Millisecond), interval, []Bucket{
+		{Sum: -3, Count: 1, Min: -3, Max: -3, Last: -3, SumSq: 9},
+		{Sum: 4, Count: 1, Min: 4, Max: 4, Last: 4, SumSq: 16},
+	})
+
+	m, err := a.Merge(b)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, m.Len())
+	assert.True(t, start.Equal(m.Start()))
+	assert.Equal(t, Bucket{Sum: 1, Count: 1, Min: 1, Max: 1, Last: 1, SumSq: 1}, m.Bucket(0))
+	assert.Equal(t, Bucket{Sum: -1, Count: 2, Min: -3, Max: 2, Last: -3, SumSq: 13}, m.Bucket(1))
+	assert.Equal(t, 4.0, m.Bucket(2).Sum)
+	assert.Equal(t, int64(4), m.Total().Count)
+
+	// merging an earlier snapshot extends the range backwards
+	c := NewSnapshot(start.Add(-2*interval), interval, []Bucket{{Sum: 5, Count: 1}})
+	m, err = a.Merge(c)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, m.Len())
+	assert.True(t, start.Add(-2*interval).Equal(m.Start()))
+	assert.Equal(t, 5.0, m.Bucket(0).Sum)
+	assert.Equal(t, int64(0), m.Bucket(1).Count)
+
+	_, err = a.Merge(NewSnapshot(start, time.Minute, nil))
+	assert.NotNil(t, err)
+
+	// far apart snapshots would need a bucket for every interval between them
+	_, err = a.Merge(NewSnapshot(start.Add(24*365*time.Hour), interval, []Bucket{{Count: 1}}))
+	assert.Equal(t, errMergeRange, err)
+	_, err = a.Merge(NewSnapshot(start.Add(-24*365*time.Hour), interval, []Bucket{{Count: 1}}))
+	assert.Equal(t, errMergeRange, err)
+	_, err = a.Merge(NewSnapshot(start.Add(maxMergedBuckets*interval-time.Minute), interval, make([]Bucket, 120)))
+	assert.Equal(t, errMergeRange, err)
+}
+
+func TestSnapshotEncoding(t *testing.T) {
+	s := NewSnapshot(time.Unix(1600000000, 123), time.Second, []Bucket{
+		{},
+		{Sum: 7.5, Count: 3, Min: -1, Max: 5, Last: 3.5, SumSq: 38.25},
+		{},
+	})
+
+	data, err := s.MarshalBinary()
+	assert.Nil(t, err)
+	var bin Snapshot
+	assert.Nil(t, bin.UnmarshalBinary(data))
+	assert.Equal(t, s.Buckets(), bin.Buckets())
+	assert.Equal(t, s.Interval(), bin.Interval())
+	assert.True(t, s.Start().Equal(bin.Start()))
+	assert.NotNil(t, bin.UnmarshalBinary(data[:len(data)-1]))
+
+	// counts above math.MaxInt64 would turn negative
+	data = appendVarint([]byte{snapshotVersion}, 0)
+	data = appendVarint(data, int64(time.Second))
+	data = appendUvarint(data, 1)
+	data = appendUvarint(data, 1<<63)
+	data = append(data, make([]byte, 5*8)...)
+	assert.Equal(t, errInvalidSnapshot, bin.UnmarshalBinary(data))
+
+	data, err = json.Marshal(s)
+	assert.Nil(t, err)
+	var js Snapshot
+	assert.Nil(t, json.Unmarshal(data, &js))
+	assert.Equal(t, s.Buckets(), js.Buckets())
+	assert.Equal(t, s.Interval(), js.Interval())
+	assert.True(t, s.Start().Equal(js.Start()))
+}
@@ -0,0 +1,281 @@
+package statsd
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zjbztianya/go-misc/metrics"
+)
+
+const (
+	// DefaultMaxPacketSize keeps a packet inside one ethernet frame:
+	// 1500 MTU - 20 bytes IPv4 header - 8 bytes UDP header, minus some headroom for tunnels.
+	DefaultMaxPacketSize = 1432
+	DefaultFlushInterval = 10 * time.Second
+)
+
+type Config struct {
+	Addr          string         // statsd server address, such as 127.0.0.1:8125
+	Prefix        string         // prepended to every metric name, such as "myapp."
+	Tags          metrics.Labels // added to every metric
+	DogStatsD     bool           // emit tags in DogStatsD format, plain StatsD has no tags
+	FlushInterval time.Duration
+	MaxPacketSize int
+	// SampleRate is the probability of sending the counters of a metric on a flush, 1 if 0.
+	// Sent counters carry |@<rate> so the server scales them back, gauges are always sent.
+	SampleRate float64
+}
+
+// Exporter periodically pushes the metrics of a registry as StatsD lines over UDP.
+// Gauges and sliding counters are sent as gauges, counters and meters as the delta
+// since the previous flush, timers as gauges of their window count, mean and
+// percentiles in milliseconds. Histograms only keep bucket counts, not samples, so they
+// send the deltas of <name>.count, <name>.sum and of the cumulative bucket counts
+// <name>.le_<bound> as counters, e.g. latency.le_0_5 for the bucket up to 0.5.
+type Exporter struct {
+	cfg  Config
+	reg  *metrics.Registry
+	conn net.Conn
+
+	mu         sync.Mutex // serializes flushes
+	counters   map[string]float64
+	histograms map[string][]metrics.HistogramBucket
+	buf        bytes.Buffer
+	line       []byte
+	random     func() float64
+
+	done      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+	closeErr  error
+}
+
+func NewExporter(reg *metrics.Registry, c *Config) (*Exporter, error) {
+	if c.Addr == "" {
+		return nil, errors.New("statsd address must not be empty")
+	}
+	cfg := *c
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = DefaultFlushInterval
+	}
+	if cfg.MaxPacketSize <= 0 {
+		cfg.MaxPacketSize = DefaultMaxPacketSize
+	}
+	if cfg.SampleRate < 0 || cfg.SampleRate > 1 {
+		return nil, errors.New("statsd sample rate must be in [0, 1]")
+	}
+	if cfg.SampleRate == 0 {
+		cfg.SampleRate = 1
+	}
+	conn, err := net.Dial("udp", cfg.Addr)
+	if err != nil {
+		return nil, err
+	}
+	return &Exporter{
+		cfg:        cfg,
+		reg:        reg,
+		conn:       conn,
+		counters:   make(map[string]float64),
+		histograms: make(map[string][]metrics.HistogramBucket),
+		random:     rand.Float64,
+		done:       make(chan struct{}),
+	}, nil
+}
+
+// Start flushes every FlushInterval in the background until Close is called.
+func (e *Exporter) Start() {
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		ticker := time.NewTicker(e.cfg.FlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				e.Flush()
+			case <-e.done:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops the background flushing, flushes one last time and closes the connection.
+// Later calls return the error of the first one.
+func (e *Exporter) Close() error {
+	e.closeOnce.Do(func() {
+		close(e.done)
+		e.wg.Wait()
+		e.closeErr = e.Flush()
+		if err := e.conn.Close(); e.closeErr == nil {
+			e.closeErr = err
+		}
+	})
+	return e.closeErr
+}
+
+// Flush sends the current value of every registered metric, returning the first send error.
+func (e *Exporter) Flush() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var err error
+	send := func() {
+		if e.buf.Len() == 0 {
+			return
+		}
+		if _, werr := e.conn.Write(e.buf.Bytes()); werr != nil && err == nil {
+			err = werr
+		}
+		e.buf.Reset()
+	}
+	emit := func(name string, tags metrics.Labels, value float64, typ string) {
+		rate := 1.0
+		if typ == "c" {
+			rate = e.cfg.SampleRate
+		}
+		e.line = e.appendLine(e.line[:0], name, tags, value, typ, rate)
+		if e.buf.Len() > 0 && e.buf.Len()+1+len(e.line) > e.cfg.MaxPacketSize {
+			send()
+		}
+		if e.buf.Len() > 0 {
+			e.buf.WriteByte('\n')
+		}
+		e.buf.Write(e.line)
+	}
+
+	e.reg.Each(func(name string, labels metrics.Labels, metric interface{}) {
+		key := name + labels.String()
+		switch m := metric.(type) {
+		case metrics.Gauge:
+			emit(name, labels, m.Value(), "g")
+		case metrics.SlidingCounter:
+			emit(name, labels, m.Sum(), "g")
+		case metrics.ValueCounter:
+			if delta := e.counterDelta(key, m.Value()); delta != 0 && e.sampled() {
+				emit(name, labels, delta, "c")
+			}
+		case metrics.Meter:
+			if delta := e.counterDelta(key, float64(m.Count())); delta != 0 && e.sampled() {
+				emit(name, labels, delta, "c")
+			}
+		case metrics.Timer:
+			s := m.Snapshot()
+			emit(name+".count", labels, float64(s.Count), "g")
+			emit(name+".mean", labels, durationMillis(s.Mean), "g")
+			emit(name+".p50", labels, durationMillis(s.Percentile(0.5)), "g")
+			emit(name+".p99", labels, durationMillis(s.Percentile(0.99)), "g")
+		case metrics.Histogram:
+			buckets := m.Buckets()
+			last := e.histograms[key]
+			e.histograms[key] = buckets
+			// the lines of a histogram are sampled together to stay consistent
+			if !e.sampled() {
+				return
+			}
+			var count int64
+			var sum float64
+			for i, b := range buckets {
+				count += b.Count
+				sum += b.Sum
+				if i < len(last) {
+					count -= last[i].Count
+					sum -= last[i].Sum
+				}
+				if count > 0 {
+					emit(name+".le_"+boundName(b.UpperBound), labels, float64(count), "c")
+				}
+			}
+			if count > 0 {
+				emit(name+".count", labels, float64(count), "c")
+				emit(name+".sum", labels, sum, "c")
+			}
+		}
+	})
+	send()
+	return err
+}
+
+// boundName formats a bucket upper bound as a metric name part, dots separate the
+// parts of StatsD names.
+func boundName(bound float64) string {
+	if math.IsInf(bound, 1) {
+		return "inf"
+	}
+	return strings.Replace(strconv.FormatFloat(bound, 'g', -1, 64), ".", "_", -1)
+}
+
+func durationMillis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// sampled reports whether the counters of a metric are sent on this flush. The deltas
+// of the others are dropped, the server scales the sent ones by the inverse of the rate.
+func (e *Exporter) sampled() bool {
+	return e.cfg.SampleRate >= 1 || e.random() < e.cfg.SampleRate
+}
+
+// counterDelta returns how much a counter grew since the previous flush.
+func (e *Exporter) counterDelta(key string, v float64) float64 {
+	delta := v - e.counters[key]
+	e.counters[key] = v
+	return delta
+}
+
+// appendLine formats one StatsD line: <prefix><name>:<value>|<type>[|@<rate>][|#<tags>]
+func (e *Exporter) appendLine(b []byte, name string, tags metrics.Labels, value float64, typ string, rate float64) []byte {
+	b = appendSanitized(b, e.cfg.Prefix)
+	b = appendSanitized(b, name)
+	b = append(b, ':')
+	b = strconv.AppendFloat(b, value, 'f', -1, 64)
+	b = append(b, '|')
+	b = append(b, typ...)
+	if rate < 1 {
+		b = append(b, "|@"...)
+		b = strconv.AppendFloat(b, rate, 'f', -1, 64)
+	}
+	if e.cfg.DogStatsD && len(tags)+len(e.cfg.Tags) > 0 {
+		b = append(b, "|#"...)
+		b = appendTags(b, e.cfg.Tags, false)
+		b = appendTags(b, tags, len(e.cfg.Tags) > 0)
+	}
+	return b
+}
+
+func appendTags(b []byte, tags metrics.Labels, comma bool) []byte {
+	for _, k := range tags.Names() {
+		if comma {
+			b = append(b, ',')
+		}
+		comma = true
+		b = appendSanitized(b, k)
+		if v := tags[k]; v != "" {
+			b = append(b, ':')
+			b = appendSanitized(b, v)
+		}
+	}
+	return b
+}
+
+// appendSanitized replaces the characters with a meaning in the StatsD protocol.
+func appendSanitized(b []byte, s string) []byte {
+	if !strings.ContainsAny(s, ":|@#,\n") {
+		return append(b, s...)
+	}
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case ':', '|', '@', '#', ',', '\n':
+			b = append(b, '_')
+		default:
+			b = append(b, c)
+		}
+	}
+	return b
+}
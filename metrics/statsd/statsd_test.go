@@ -0,0 +1,209 @@
+package statsd
+
+import (
+	"net"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zjbztianya/go-misc/metrics"
+)
+
+func listen(t *testing.T) net.PacketConn {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+// readPackets reads packets until no new one arrives for a short while.
+func readPackets(t *testing.T, conn net.PacketConn) []string {
+	var packets []string
+	buf := make([]byte, 65536)
+	for {
+		conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			return packets
+		}
+		packets = append(packets, string(buf[:n]))
+	}
+}
+
+func readLines(t *testing.T, conn net.PacketConn) []string {
+	var lines []string
+	for _, p := range readPackets(t, conn) {
+		lines = append(lines, strings.Split(p, "\n")...)
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+func TestExporterFlush(t *testing.T) {
+	conn := listen(t)
+	defer conn.Close()
+
+	reg := metrics.NewRegistry()
+	g := metrics.NewGauge()
+	c := metrics.NewCounter()
+	h := metrics.NewHistogram(1, 10)
+	reg.Register("temperature", g)
+	reg.RegisterWithLabels("requests", metrics.Labels{"path": "/login"}, c)
+	reg.Register("latency", h)
+	m := metrics.NewMeter()
+	reg.Register("events", m)
+	tm := metrics.NewTimer(10, time.Second, 0.1)
+	reg.Register("call", tm)
+
+	e, err := NewExporter(reg, &Config{
+		Addr:      conn.LocalAddr().String(),
+		Prefix:    "app.",
+		Tags:      metrics.Labels{"env": "test"},
+		DogStatsD: true,
+	})
+	assert.Nil(t, err)
+	defer e.Close()
+
+	g.Set(-1.5)
+	c.Add(3)
+	h.Observe(0.5)
+	h.Observe(0.7)
+	h.Observe(5)
+	m.Mark(7)
+	tm.UpdateDuration(50 * time.Millisecond)
+	assert.Nil(t, e.Flush())
+	assert.Equal(t, []string{
+		"app.call.count:1|g|#env:test",
+		"app.call.mean:50|g|#env:test",
+		"app.call.p50:50|g|#env:test",
+		"app.call.p99:99|g|#env:test",
+		"app.events:7|c|#env:test",
+		"app.latency.count:3|c|#env:test",
+		"app.latency.le_10:3|c|#env:test",
+		"app.latency.le_1:2|c|#env:test",
+		"app.latency.le_inf:3|c|#env:test",
+		"app.latency.sum:6.2|c|#env:test",
+		"app.requests:3|c|#env:test,path:/login",
+		"app.temperature:-1.5|g|#env:test",
+	}, readLines(t, conn))
+
+	// counters and histograms only send what changed since the last flush
+	c.Inc()
+	h.Observe(5)
+	reg.Unregister("call", nil)
+	assert.Nil(t, e.Flush())
+	assert.Equal(t, []string{
+		"app.latency.count:1|c|#env:test",
+		"app.latency.le_10:1|c|#env:test",
+		"app.latency.le_inf:1|c|#env:test",
+		"app.latency.sum:5|c|#env:test",
+		"app.requests:1|c|#env:test,path:/login",
+		"app.temperature:-1.5|g|#env:test",
+	}, readLines(t, conn))
+	assert.Equal(t, "0_25", boundName(0.25))
+}
+
+func TestExporterPlainStatsD(t *testing.T) {
+	conn := listen(t)
+	defer conn.Close()
+
+	reg := metrics.NewRegistry()
+	reg.RegisterWithLabels("bad:name|x", metrics.Labels{"k": "v"}, metrics.NewGauge())
+	e, err := NewExporter(reg, &Config{Addr: conn.LocalAddr().String()})
+	assert.Nil(t, err)
+	defer e.Close()
+
+	assert.Nil(t, e.Flush())
+	assert.Equal(t, []string{"bad_name_x:0|g"}, readLines(t, conn))
+}
+
+func TestExporterBatching(t *testing.T) {
+	conn := listen(t)
+	defer conn.Close()
+
+	reg := metrics.NewRegistry()
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		reg.Register("gauge_"+name, metrics.NewGauge())
+	}
+	// every line is "gauge_x:0|g", 11 bytes, so two lines fit a packet
+	e, err := NewExporter(reg, &Config{Addr: conn.LocalAddr().String(), MaxPacketSize: 24})
+	assert.Nil(t, err)
+	defer e.Close()
+
+	assert.Nil(t, e.Flush())
+	packets := readPackets(t, conn)
+	assert.Len(t, packets, 3)
+	for _, p := range packets {
+		assert.LessOrEqual(t, len(p), 24)
+	}
+}
+
+func TestExporterSampleRate(t *testing.T) {
+	conn := listen(t)
+	defer conn.Close()
+
+	reg := metrics.NewRegistry()
+	g := metrics.NewGauge()
+	c := metrics.NewCounter()
+	h := metrics.NewHistogram(1)
+	reg.Register("gauge", g)
+	reg.Register("counter", c)
+	reg.Register("histogram", h)
+	e, err := NewExporter(reg, &Config{Addr: conn.LocalAddr().String(), SampleRate: 0.25, DogStatsD: true, Tags: metrics.Labels{"env": "test"}})
+	assert.Nil(t, err)
+	defer e.Close()
+
+	// the histogram is dropped, the counter is sent
+	draws := []float64{0.1, 0.9}
+	e.random = func() float64 {
+		r := draws[0]
+		draws = draws[1:]
+		return r
+	}
+	c.Add(2)
+	h.Observe(0.5)
+	assert.Nil(t, e.Flush())
+	assert.Equal(t, []string{
+		"counter:2|c|@0.25|#env:test",
+		"gauge:0|g|#env:test",
+	}, readLines(t, conn))
+
+	// the dropped deltas are not sent later
+	draws = []float64{0.9, 0.1}
+	h.Observe(2)
+	c.Inc()
+	assert.Nil(t, e.Flush())
+	assert.Equal(t, []string{
+		"gauge:0|g|#env:test",
+		"histogram.count:1|c|@0.25|#env:test",
+		"histogram.le_inf:1|c|@0.25|#env:test",
+		"histogram.sum:2|c|@0.25|#env:test",
+	}, readLines(t, conn))
+	e.random = func() float64 { return 0 }
+
+	_, err = NewExporter(reg, &Config{Addr: conn.LocalAddr().String(), SampleRate: 1.5})
+	assert.NotNil(t, err)
+}
+
+func TestExporterStart(t *testing.T) {
+	conn := listen(t)
+	defer conn.Close()
+
+	reg := metrics.NewRegistry()
+	reg.Register("gauge", metrics.NewGauge())
+	e, err := NewExporter(reg, &Config{Addr: conn.LocalAddr().String(), FlushInterval: 20 * time.Millisecond})
+	assert.Nil(t, err)
+	e.Start()
+	time.Sleep(50 * time.Millisecond)
+	assert.Nil(t, e.Close())
+	assert.Nil(t, e.Close())
+	assert.GreaterOrEqual(t, len(readPackets(t, conn)), 2)
+}
+
+func TestNewExporter(t *testing.T) {
+	_, err := NewExporter(metrics.NewRegistry(), &Config{})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,145 @@
+package metrics
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+var _ Timer = (*timer)(nil)
+
+// Timer measures call latencies over a sliding window, durations are recorded in
+// seconds into a windowed histogram.
+type Timer interface {
+	// Time records how long fn takes.
+	Time(fn func())
+	// Since records the time elapsed since start.
+	Since(start time.Time)
+	UpdateDuration(d time.Duration)
+	Snapshot() TimerSnapshot
+}
+
+// TimerSnapshot summarizes the durations recorded in the window of a Timer,
+// all fields are 0 when the window is empty.
+type TimerSnapshot struct {
+	Count int64
+	Rate  float64 // calls per second over the full window duration
+	Sum   time.Duration
+	Min   time.Duration
+	Max   time.Duration
+	Mean  time.Duration
+
+	buckets []HistogramBucket
+}
+
+// Percentile estimates the q-quantile (0 <= q <= 1) from the histogram buckets.
+func (s TimerSnapshot) Percentile(q float64) time.Duration {
+	return secondsToDuration(bucketQuantile(s.buckets, q))
+}
+
+// Buckets returns the histogram buckets of the window with upper bounds in seconds.
+func (s TimerSnapshot) Buckets() []HistogramBucket {
+	buckets := make([]HistogramBucket, len(s.buckets))
+	copy(buckets, s.buckets)
+	return buckets
+}
+
+type timer struct {
+	win *SlidingWindow
+}
+
+// NewTimer creates a timer whose window has size buckets of interval each, bounds are
+// the histogram upper bounds in seconds, DefBuckets are used when none are given.
+func NewTimer(size int, interval time.Duration, bounds ...float64) Timer {
+	bounds = finiteBounds(bounds)
+	return &timer{win: NewSlidingWindow(size, interval, WithAggregator(func() Aggregator {
+		return newHistogramAggregate(bounds)
+	}))}
+}
+
+func (t *timer) Time(fn func()) {
+	start := time.Now()
+	fn()
+	t.Since(start)
+}
+
+func (t *timer) Since(start time.Time) {
+	t.UpdateDuration(time.Since(start))
+}
+
+func (t *timer) UpdateDuration(d time.Duration) {
+	t.win.Add(d.Seconds())
+}
+
+func (t *timer) Snapshot() TimerSnapshot {
+	total := t.win.Aggregate().(*histogramAggregate)
+	s := TimerSnapshot{buckets: total.histogramBuckets()}
+	if total.Count == 0 {
+		return s
+	}
+	s.Count = total.Count
+	s.Rate = float64(total.Count) / (time.Duration(t.win.Size()) * t.win.ring.interval).Seconds()
+	s.Sum = secondsToDuration(total.Sum)
+	s.Min = secondsToDuration(total.Min)
+	s.Max = secondsToDuration(total.Max)
+	s.Mean = secondsToDuration(total.Sum / float64(total.Count))
+	return s
+}
+
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(math.Round(s * float64(time.Second)))
+}
+
+var _ Aggregator = (*histogramAggregate)(nil)
+
+// histogramAggregate counts samples in fixed buckets on top of the sample stats of Bucket.
+type histogramAggregate struct {
+	Bucket
+	bounds []float64 // shared, read only
+	counts []int64   // one more than bounds, for +Inf
+	sums   []float64
+}
+
+func newHistogramAggregate(bounds []float64) *histogramAggregate {
+	return &histogramAggregate{
+		bounds: bounds,
+		counts: make([]int64, len(bounds)+1),
+		sums:   make([]float64, len(bounds)+1),
+	}
+}
+
+func (h *histogramAggregate) Add(v float64) {
+	h.Bucket.Add(v)
+	i := sort.SearchFloat64s(h.bounds, v)
+	h.counts[i]++
+	h.sums[i] += v
+}
+
+func (h *histogramAggregate) Reset() {
+	h.Bucket.Reset()
+	for i := range h.counts {
+		h.counts[i] = 0
+		h.sums[i] = 0
+	}
+}
+
+func (h *histogramAggregate) Merge(a Aggregator) {
+	o := a.(*histogramAggregate)
+	h.Bucket.merge(&o.Bucket)
+	for i := range h.counts {
+		h.counts[i] += o.counts[i]
+		h.sums[i] += o.sums[i]
+	}
+}
+
+func (h *histogramAggregate) histogramBuckets() []HistogramBucket {
+	buckets := make([]HistogramBucket, len(h.counts))
+	for i := range buckets {
+		upper := math.Inf(1)
+		if i < len(h.bounds) {
+			upper = h.bounds[i]
+		}
+		buckets[i] = HistogramBucket{UpperBound: upper, Count: h.counts[i], Sum: h.sums[i]}
+	}
+	return buckets
+}
@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTimer(t *testing.T) {
+	assert.NotNil(t, NewTimer(10, time.Second))
+	assert.Panics(t, func() {
+		NewTimer(0, time.Second)
+	})
+
+	s := NewTimer(10, time.Second).Snapshot()
+	assert.Equal(t, int64(0), s.Count)
+	assert.Equal(t, time.Duration(0), s.Mean)
+	assert.Equal(t, time.Duration(0), s.Percentile(0.99))
+}
+
+func TestTimerSnapshot(t *testing.T) {
+	tm := NewTimer(4, time.Second, 0.01, 0.1, 1)
+	for i := 0; i < 90; i++ {
+		tm.UpdateDuration(5 * time.Millisecond)
+	}
+	for i := 0; i < 10; i++ {
+		tm.UpdateDuration(500 * time.Millisecond)
+	}
+
+	s := tm.Snapshot()
+	assert.Equal(t, int64(100), s.Count)
+	assert.Equal(t, 25.0, s.Rate)
+	assert.Equal(t, 5450*time.Millisecond, s.Sum)
+	assert.Equal(t, 5*time.Millisecond, s.Min)
+	assert.Equal(t, 500*time.Millisecond, s.Max)
+	assert.Equal(t, 54500*time.Microsecond, s.Mean)
+	assert.Equal(t, 5*time.Millisecond, s.Percentile(0.45))
+	assert.Equal(t, 550*time.Millisecond, s.Percentile(0.95))
+	assert.Len(t, s.Buckets(), 4)
+}
+
+func TestTimerWindow(t *testing.T) {
+	interval := 50 * time.Millisecond
+	tm := NewTimer(2, interval)
+	tm.Time(func() {})
+	tm.Since(time.Now())
+	assert.Equal(t, int64(2), tm.Snapshot().Count)
+	time.Sleep(2 * interval)
+	assert.Equal(t, int64(0), tm.Snapshot().Count)
+	tm.UpdateDuration(time.Millisecond)
+	assert.Equal(t, int64(1), tm.Snapshot().Count)
+}
+
+func BenchmarkTimerUpdateDuration(b *testing.B) {
+	tm := NewTimer(10, time.Second)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tm.UpdateDuration(time.Duration(i))
+	}
+}
@@ -0,0 +1,228 @@
+package slo
+
+import (
+	"sync"
+	"time"
+
+	"github.com/zjbztianya/go-misc/metrics"
+)
+
+const (
+	DefaultPeriod  = 30 * 24 * time.Hour
+	DefaultBuckets = 60
+)
+
+// BurnRateRule fires when the error budget burns at least BurnRate times faster than
+// the rate that exhausts it exactly at the end of the period, over both windows.
+// The short window makes the alert reset soon after the errors stop.
+type BurnRateRule struct {
+	Name        string // such as "page" or "ticket"
+	LongWindow  time.Duration
+	ShortWindow time.Duration
+	BurnRate    float64
+}
+
+// DefaultRules are the multiwindow, multi-burn-rate alerts recommended by the SRE workbook
+// https://sre.google/workbook/alerting-on-slos/#6-multiwindow-multi-burn-rate-alerts
+// for a 30 days period: page when 2% of the budget is spent in 1 hour or 5% in 6 hours,
+// open a ticket when 10% is spent in 3 days. The 1 day rule, a ticket when 10% is spent
+// in 1 day, is not in the table of the workbook: it reports such burns before the 3 days
+// window does.
+var DefaultRules = []BurnRateRule{
+	{Name: "page", LongWindow: time.Hour, ShortWindow: 5 * time.Minute, BurnRate: 14.4},
+	{Name: "page", LongWindow: 6 * time.Hour, ShortWindow: 30 * time.Minute, BurnRate: 6},
+	{Name: "ticket", LongWindow: 24 * time.Hour, ShortWindow: 2 * time.Hour, BurnRate: 3},
+	{Name: "ticket", LongWindow: 3 * 24 * time.Hour, ShortWindow: 6 * time.Hour, BurnRate: 1},
+}
+
+// Alert is the state of a rule, passed to Config.OnChange when it starts or stops firing.
+type Alert struct {
+	Rule          BurnRateRule
+	Firing        bool
+	LongBurnRate  float64
+	ShortBurnRate float64
+}
+
+type Config struct {
+	Objective float64       // target ratio of good events, such as 0.999
+	Period    time.Duration // the error budget period, DefaultPeriod if 0
+	Rules     []BurnRateRule
+	Buckets   int // buckets of every sliding window, DefaultBuckets if 0
+	OnChange  func(a Alert)
+}
+
+// SLO tracks the error budget of a service level objective from good and bad events
+// counted in sliding windows, and evaluates burn rate alerts on them.
+type SLO struct {
+	objective float64
+	period    time.Duration
+	rules     []BurnRateRule
+	windows   map[time.Duration]metrics.SlidingCounter // bad events add 1, good events 0
+	onChange  func(a Alert)
+
+	mu        sync.Mutex // guards firing and serializes evaluations
+	firing    []bool
+	pending   []Alert // changes not passed to OnChange yet
+	notifying bool    // a goroutine is calling OnChange
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New creates an SLO, DefaultRules are used when the config has none. It panics if the
+// objective is not in (0, 1) or a window is shorter than its buckets.
+func New(c *Config) *SLO {
+	if c.Objective <= 0 || c.Objective >= 1 {
+		panic("slo objective must be in (0, 1)")
+	}
+	s := &SLO{
+		objective: c.Objective,
+		period:    c.Period,
+		rules:     c.Rules,
+		windows:   make(map[time.Duration]metrics.SlidingCounter),
+		onChange:  c.OnChange,
+	}
+	if s.period <= 0 {
+		s.period = DefaultPeriod
+	}
+	if len(s.rules) == 0 {
+		s.rules = DefaultRules
+	}
+	buckets := c.Buckets
+	if buckets <= 0 {
+		buckets = DefaultBuckets
+	}
+	s.firing = make([]bool, len(s.rules))
+
+	durations := []time.Duration{s.period}
+	for _, r := range s.rules {
+		durations = append(durations, r.LongWindow, r.ShortWindow)
+	}
+	for _, d := range durations {
+		if _, ok := s.windows[d]; ok {
+			continue
+		}
+		interval := d / time.Duration(buckets)
+		if interval <= 0 {
+			panic("slo window must not be shorter than its buckets")
+		}
+		s.windows[d] = metrics.NewSlidingCounter(buckets, interval)
+	}
+	return s
+}
+
+func (s *SLO) MarkGood() {
+	s.Record(true)
+}
+
+func (s *SLO) MarkBad() {
+	s.Record(false)
+}
+
+func (s *SLO) Record(good bool) {
+	v := 1.0
+	if good {
+		v = 0
+	}
+	for _, w := range s.windows {
+		w.Add(v)
+	}
+}
+
+// ErrorRate returns the ratio of bad events over window, which must be the period or
+// one of the rule windows, 0 for other windows or without events.
+func (s *SLO) ErrorRate(window time.Duration) float64 {
+	if w, ok := s.windows[window]; ok {
+		return w.Avg()
+	}
+	return 0
+}
+
+// BurnRate returns how many times faster than allowed the error budget burns over window.
+func (s *SLO) BurnRate(window time.Duration) float64 {
+	return s.ErrorRate(window) / (1 - s.objective)
+}
+
+// ErrorBudgetRemaining returns the ratio of the error budget of the period not spent yet,
+// it is negative when the objective is missed.
+func (s *SLO) ErrorBudgetRemaining() float64 {
+	return 1 - s.BurnRate(s.period)
+}
+
+// Evaluate checks every rule, calls OnChange for the rules that started or stopped
+// firing and returns the alerts currently firing. OnChange is called in order, one
+// change at a time, without holding the lock of s, so it may call the methods of s.
+// The changes found while another call runs OnChange are passed by that call.
+func (s *SLO) Evaluate() []Alert {
+	s.mu.Lock()
+	var firing []Alert
+	for i, r := range s.rules {
+		a := Alert{Rule: r, LongBurnRate: s.BurnRate(r.LongWindow), ShortBurnRate: s.BurnRate(r.ShortWindow)}
+		a.Firing = a.LongBurnRate >= r.BurnRate && a.ShortBurnRate >= r.BurnRate
+		if a.Firing {
+			firing = append(firing, a)
+		}
+		if a.Firing != s.firing[i] {
+			s.firing[i] = a.Firing
+			if s.onChange != nil {
+				s.pending = append(s.pending, a)
+			}
+		}
+	}
+	s.notify()
+	return firing
+}
+
+// notify passes the pending changes to OnChange and unlocks s.mu, which must be held.
+func (s *SLO) notify() {
+	if s.notifying {
+		s.mu.Unlock()
+		return
+	}
+	s.notifying = true
+	for len(s.pending) > 0 {
+		pending := s.pending
+		s.pending = nil
+		s.mu.Unlock()
+		for _, a := range pending {
+			s.onChange(a)
+		}
+		s.mu.Lock()
+	}
+	s.notifying = false
+	s.mu.Unlock()
+}
+
+// Start evaluates every interval in the background until Stop is called.
+func (s *SLO) Start(interval time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stop != nil {
+		return
+	}
+	s.stop = make(chan struct{})
+	s.wg.Add(1)
+	go func(stop chan struct{}) {
+		defer s.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.Evaluate()
+			case <-stop:
+				return
+			}
+		}
+	}(s.stop)
+}
+
+func (s *SLO) Stop() {
+	s.mu.Lock()
+	if s.stop != nil {
+		close(s.stop)
+		s.stop = nil
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
@@ -0,0 +1,173 @@
+package slo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestSLO(onChange func(a Alert)) *SLO {
+	return New(&Config{
+		Objective: 0.9,
+		Period:    2 * time.Second,
+		Rules: []BurnRateRule{
+			{Name: "page", LongWindow: 400 * time.Millisecond, ShortWindow: 100 * time.Millisecond, BurnRate: 5},
+			{Name: "ticket", LongWindow: time.Second, ShortWindow: 200 * time.Millisecond, BurnRate: 2},
+		},
+		Buckets:  10,
+		OnChange: onChange,
+	})
+}
+
+func TestNew(t *testing.T) {
+	s := New(&Config{Objective: 0.999})
+	assert.Equal(t, DefaultPeriod, s.period)
+	assert.Equal(t, DefaultRules, s.rules)
+	assert.Len(t, s.windows, 8)
+	assert.Equal(t, 1.0, s.ErrorBudgetRemaining())
+	assert.Empty(t, s.Evaluate())
+
+	assert.Panics(t, func() {
+		New(&Config{Objective: 1})
+	})
+	assert.Panics(t, func() {
+		New(&Config{Objective: 0.9, Period: time.Nanosecond})
+	})
+}
+
+func TestSLOErrorBudget(t *testing.T) {
+	s := newTestSLO(nil)
+	for i := 0; i < 95; i++ {
+		s.MarkGood()
+	}
+	for i := 0; i < 5; i++ {
+		s.MarkBad()
+	}
+	assert.InDelta(t, 0.05, s.ErrorRate(2*time.Second), 1e-9)
+	assert.InDelta(t, 0.5, s.BurnRate(time.Second), 1e-9)
+	assert.InDelta(t, 0.5, s.ErrorBudgetRemaining(), 1e-9)
+	assert.Equal(t, 0.0, s.ErrorRate(time.Minute))
+
+	for i := 0; i < 10; i++ {
+		s.Record(false)
+	}
+	assert.Less(t, s.ErrorBudgetRemaining(), 0.0)
+}
+
+func TestSLOEvaluate(t *testing.T) {
+	var changes []Alert
+	s := newTestSLO(func(a Alert) {
+		changes = append(changes, a)
+	})
+
+	// a 60% error rate burns the budget 6 times faster than allowed
+	for i := 0; i < 40; i++ {
+		s.MarkGood()
+	}
+	for i := 0; i < 60; i++ {
+		s.MarkBad()
+	}
+	firing := s.Evaluate()
+	assert.Len(t, firing, 2)
+	assert.Equal(t, "page", firing[0].Rule.Name)
+	assert.InDelta(t, 6.0, firing[0].LongBurnRate, 1e-9)
+	assert.Len(t, changes, 2)
+	assert.True(t, changes[0].Firing)
+
+	// no change, no callback
+	s.Evaluate()
+	assert.Len(t, changes, 2)
+
+	// the short windows recover first once errors stop
+	time.Sleep(250 * time.Millisecond)
+	for i := 0; i < 100; i++ {
+		s.MarkGood()
+	}
+	assert.Empty(t, s.Evaluate())
+	assert.Len(t, changes, 4)
+	assert.False(t, changes[2].Firing)
+	assert.False(t, changes[3].Firing)
+	assert.Greater(t, s.BurnRate(time.Second), 2.0)
+}
+
+func TestSLOOnChangeReentrant(t *testing.T) {
+	var s *SLO
+	var firing [][]Alert
+	s = newTestSLO(func(a Alert) {
+		firing = append(firing, s.Evaluate())
+	})
+	for i := 0; i < 10; i++ {
+		s.MarkBad()
+	}
+	done := make(chan []Alert)
+	go func() {
+		done <- s.Evaluate()
+	}()
+	select {
+	case alerts := <-done:
+		assert.Len(t, alerts, 2)
+		assert.Len(t, firing, 2)
+	case <-time.After(time.Second):
+		t.Fatal("OnChange calling Evaluate deadlocked")
+	}
+}
+
+func TestSLOOnChangeOrder(t *testing.T) {
+	var changes []Alert
+	release := make(chan struct{})
+	s := newTestSLO(func(a Alert) {
+		if len(changes) == 0 {
+			<-release
+		}
+		changes = append(changes, a)
+	})
+	for i := 0; i < 10; i++ {
+		s.MarkBad()
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.Evaluate()
+	}()
+
+	// the alerts resolve while the first call is still passing them as firing
+	time.Sleep(250 * time.Millisecond)
+	for i := 0; i < 100; i++ {
+		s.MarkGood()
+	}
+	assert.Empty(t, s.Evaluate())
+	close(release)
+	<-done
+	assert.Len(t, changes, 4)
+	for i, a := range changes {
+		assert.Equal(t, i < 2, a.Firing)
+	}
+}
+
+func TestSLOStart(t *testing.T) {
+	changes := make(chan Alert, 2)
+	s := newTestSLO(func(a Alert) {
+		changes <- a
+	})
+	for i := 0; i < 10; i++ {
+		s.MarkBad()
+	}
+	s.Start(10 * time.Millisecond)
+	s.Start(10 * time.Millisecond)
+	defer s.Stop()
+	select {
+	case a := <-changes:
+		assert.True(t, a.Firing)
+	case <-time.After(time.Second):
+		t.Fatal("no alert fired")
+	}
+}
+
+func BenchmarkSLORecord(b *testing.B) {
+	s := New(&Config{Objective: 0.999})
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Record(i%1000 != 0)
+	}
+}
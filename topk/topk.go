@@ -0,0 +1,198 @@
+package topk
+
+import (
+	"container/heap"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/zjbztianya/go-misc/metrics"
+)
+
+// Item is a heavy hitter, its true count over the window is in [Count-Error, Count].
+type Item struct {
+	Key   string
+	Count uint64
+	Error uint64
+}
+
+type Option func(*TopK)
+
+// WithCapacity sets how many keys every bucket tracks, 10 times k by default.
+// The more keys, the smaller the error bounds.
+func WithCapacity(capacity int) Option {
+	return func(t *TopK) {
+		t.capacity = capacity
+	}
+}
+
+// TopK tracks the k most frequent keys of a sliding window with the Space-Saving
+// algorithm of Metwally et al., "Efficient Computation of Frequent and Top-k Elements
+// in Data Streams". The window rotates like a metrics.SlidingWindow, every bucket keeps
+// its own summary and List merges the summaries still inside the window. It is safe for
+// concurrent use.
+type TopK struct {
+	mu       sync.Mutex
+	k        int
+	capacity int
+	ring     *metrics.Ring
+	buckets  []*summary
+}
+
+func New(k, size int, interval time.Duration, opts ...Option) *TopK {
+	if k <= 0 || size <= 0 {
+		panic("topk k and window size must greater than 0")
+	}
+	t := &TopK{k: k, capacity: 10 * k, ring: metrics.NewRing(size, interval)}
+	for _, opt := range opts {
+		opt(t)
+	}
+	if t.capacity < k {
+		t.capacity = k
+	}
+	t.buckets = make([]*summary, size)
+	for i := range t.buckets {
+		t.buckets[i] = newSummary(t.capacity)
+	}
+	return t
+}
+
+func (t *TopK) Inc(key string) {
+	t.Add(key, 1)
+}
+
+func (t *TopK) Add(key string, n uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.buckets[t.ring.Advance(t.reset)].add(key, n)
+}
+
+func (t *TopK) reset(i int) {
+	t.buckets[i].reset()
+}
+
+// List returns the top k keys of the window by decreasing count.
+func (t *TopK) List() []Item {
+	t.mu.Lock()
+	var valid []*summary
+	t.ring.Each(func(i int) {
+		valid = append(valid, t.buckets[i])
+	})
+	items := mergeSummaries(valid)
+	t.mu.Unlock()
+
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Count != items[j].Count {
+			return items[i].Count > items[j].Count
+		}
+		return items[i].Key < items[j].Key
+	})
+	if len(items) > t.k {
+		items = items[:t.k]
+	}
+	return items
+}
+
+// mergeSummaries sums the counts of every key, see Agarwal et al., "Mergeable Summaries":
+// a key missing from a full summary may have been counted up to its minimum count.
+func mergeSummaries(summaries []*summary) []Item {
+	merged := make(map[string]*Item)
+	for _, s := range summaries {
+		for _, c := range s.counters {
+			if _, ok := merged[c.key]; !ok {
+				merged[c.key] = &Item{Key: c.key}
+			}
+		}
+	}
+	for _, s := range summaries {
+		min := s.min()
+		for key, item := range merged {
+			if c, ok := s.keys[key]; ok {
+				item.Count += c.count
+				item.Error += c.err
+			} else {
+				item.Count += min
+				item.Error += min
+			}
+		}
+	}
+	items := make([]Item, 0, len(merged))
+	for _, item := range merged {
+		items = append(items, *item)
+	}
+	return items
+}
+
+type counter struct {
+	key   string
+	count uint64
+	err   uint64 // overestimation inherited from the evicted key
+	index int    // in the heap
+}
+
+// summary is a Space-Saving summary: a min heap of the counters by count.
+type summary struct {
+	capacity int
+	counters []*counter
+	keys     map[string]*counter
+}
+
+func newSummary(capacity int) *summary {
+	return &summary{capacity: capacity, keys: make(map[string]*counter, capacity)}
+}
+
+func (s *summary) add(key string, n uint64) {
+	if c, ok := s.keys[key]; ok {
+		c.count += n
+		heap.Fix(s, c.index)
+		return
+	}
+	if len(s.counters) < s.capacity {
+		c := &counter{key: key, count: n}
+		s.keys[key] = c
+		heap.Push(s, c)
+		return
+	}
+	// the new key takes over the counter of the least frequent one
+	c := s.counters[0]
+	delete(s.keys, c.key)
+	c.key, c.err = key, c.count
+	c.count += n
+	s.keys[key] = c
+	heap.Fix(s, 0)
+}
+
+// min returns the count a key missing from the summary may have, 0 if it is not full.
+func (s *summary) min() uint64 {
+	if len(s.counters) < s.capacity {
+		return 0
+	}
+	return s.counters[0].count
+}
+
+func (s *summary) reset() {
+	s.counters = s.counters[:0]
+	s.keys = make(map[string]*counter, s.capacity)
+}
+
+func (s *summary) Len() int           { return len(s.counters) }
+func (s *summary) Less(i, j int) bool { return s.counters[i].count < s.counters[j].count }
+
+func (s *summary) Swap(i, j int) {
+	s.counters[i], s.counters[j] = s.counters[j], s.counters[i]
+	s.counters[i].index = i
+	s.counters[j].index = j
+}
+
+func (s *summary) Push(x interface{}) {
+	c := x.(*counter)
+	c.index = len(s.counters)
+	s.counters = append(s.counters, c)
+}
+
+func (s *summary) Pop() interface{} {
+	old := s.counters
+	c := old[len(old)-1]
+	s.counters = old[:len(old)-1]
+	return c
+}
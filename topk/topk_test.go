@@ -0,0 +1,101 @@
+package topk
+
+import (
+	"math/rand"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	assert.Empty(t, New(3, 10, time.Second).List())
+	assert.Panics(t, func() {
+		New(0, 10, time.Second)
+	})
+	assert.Equal(t, 3, New(3, 1, time.Second, WithCapacity(1)).capacity)
+}
+
+func TestTopKExact(t *testing.T) {
+	tk := New(2, 10, time.Second)
+	tk.Add("a", 3)
+	tk.Inc("b")
+	tk.Add("c", 5)
+	tk.Inc("b")
+	assert.Equal(t, []Item{{Key: "c", Count: 5}, {Key: "a", Count: 3}}, tk.List())
+}
+
+func TestTopKErrorBounds(t *testing.T) {
+	// a zipf distribution over 10000 keys with room for 100 of them
+	r := rand.New(rand.NewSource(1))
+	zipf := rand.NewZipf(r, 1.2, 1, 9999)
+	tk := New(10, 1, time.Hour, WithCapacity(100))
+	exact := make(map[string]uint64)
+	for i := 0; i < 100000; i++ {
+		key := strconv.FormatUint(zipf.Uint64(), 10)
+		tk.Inc(key)
+		exact[key]++
+	}
+
+	items := tk.List()
+	assert.Len(t, items, 10)
+	for i, item := range items {
+		assert.LessOrEqual(t, item.Count-item.Error, exact[item.Key])
+		assert.GreaterOrEqual(t, item.Count, exact[item.Key])
+		// the most frequent keys are exact ranks of the distribution
+		if i < 5 {
+			assert.Equal(t, strconv.Itoa(i), item.Key)
+		}
+	}
+}
+
+func TestTopKWindow(t *testing.T) {
+	interval := 50 * time.Millisecond
+	tk := New(2, 2, interval, WithCapacity(2))
+	tk.Add("a", 10)
+	tk.Add("b", 5)
+	time.Sleep(interval)
+	tk.Add("c", 7)
+	// c may have been evicted from the full first bucket, with up to its minimum count 5
+	assert.Equal(t, []Item{{Key: "c", Count: 12, Error: 5}, {Key: "a", Count: 10}}, tk.List())
+
+	time.Sleep(interval)
+	assert.Equal(t, []Item{{Key: "c", Count: 7}}, tk.List())
+	time.Sleep(interval)
+	assert.Empty(t, tk.List())
+	tk.Inc("d")
+	assert.Equal(t, []Item{{Key: "d", Count: 1}}, tk.List())
+}
+
+func TestTopKConcurrent(t *testing.T) {
+	tk := New(3, 10, 10*time.Millisecond)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				tk.Inc(strconv.Itoa(i % (g + 2)))
+				if i%100 == 0 {
+					tk.List()
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	assert.Len(t, tk.List(), 3)
+}
+
+func BenchmarkTopKInc(b *testing.B) {
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = strconv.Itoa(i)
+	}
+	tk := New(10, 10, time.Second)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tk.Inc(keys[i%len(keys)])
+	}
+}